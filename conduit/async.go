@@ -0,0 +1,128 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+)
+
+// Query statuses reported by Conduit.
+const (
+	StatusRunning      = "Running"
+	StatusResultsReady = "ResultsReady"
+	StatusFinished     = "Finished"
+)
+
+// QueryHandle is a query that was submitted without waiting for it. Only its ID is
+// needed to pick it up again, so it can be stored and reattached from another
+// process with Attach.
+type QueryHandle struct {
+	client *ConduitClient
+	Query  QueryStruct
+}
+
+// Submit starts a query and returns as soon as Conduit has given it an ID.
+// Args are bound to placeholders as in ExecuteQuery.
+func (c *ConduitClient) Submit(ctx context.Context, sqlString string, args ...interface{}) (*QueryHandle, error) {
+	return c.SubmitWithOptions(ctx, c.Options, sqlString, args...)
+}
+
+// SubmitWithOptions is Submit with opts in place of the client's Options.
+func (c *ConduitClient) SubmitWithOptions(ctx context.Context, opts QueryOptions, sqlString string, args ...interface{}) (*QueryHandle, error) {
+	boundSql, err := Bind(sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	q, err := NewQueryWithOptions(boundSql, opts)
+	if err != nil {
+		return nil, err
+	}
+	h := &QueryHandle{client: c, Query: q}
+	release, err := c.acquireQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := c.postQuery(ctx, &h.Query)
+	if err != nil {
+		c.emit(h.Query.failed(err))
+		return nil, err
+	}
+	defer resp.Body.Close()
+	qrs, err := c.readQueryResult(&h.Query, resp)
+	if err != nil {
+		return nil, err
+	}
+	if h.Query.ActiveQueryId == "" {
+		return nil, fmt.Errorf("Conduit didn't return a queryId. Status: %v, message: %v", qrs.Status, qrs.Message)
+	}
+	if h.ready() {
+		h.Query.QueryResults = append(h.Query.QueryResults, qrs)
+	}
+	return h, nil
+}
+
+// Attach returns a handle for a query submitted earlier, possibly by another process.
+// With more than one server configured, use AttachOn so the calls reach the server running it.
+func (c *ConduitClient) Attach(queryId string) *QueryHandle {
+	return c.AttachOn("", queryId)
+}
+
+// AttachOn is Attach for a query running on server, as given by the handle's Server.
+func (c *ConduitClient) AttachOn(server, queryId string) *QueryHandle {
+	q := QueryStruct{Options: c.Options.withDefaults(), Server: server}
+	q.ActiveQueryId = queryId
+	return &QueryHandle{client: c, Query: q}
+}
+
+// Id is the queryId to persist for a later Attach.
+func (h *QueryHandle) Id() string {
+	return h.Query.ActiveQueryId
+}
+
+// Server is the server running the query, to persist alongside Id for AttachOn.
+func (h *QueryHandle) Server() string {
+	return h.Query.Server
+}
+
+func (h *QueryHandle) ready() bool {
+	return h.Query.ActiveQueryStatus == StatusFinished || h.Query.ActiveQueryStatus == StatusResultsReady
+}
+
+// Status asks Conduit for the query's current status. Once results are ready the
+// first page is kept, so a later Results call doesn't fetch it again.
+func (h *QueryHandle) Status(ctx context.Context) (string, error) {
+	if h.ready() && len(h.Query.QueryResults) > 0 {
+		return h.Query.ActiveQueryStatus, nil
+	}
+	resp, err := h.client.getQueryResult(ctx, &h.Query)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	qrs, err := h.client.readQueryResult(&h.Query, resp)
+	if err != nil {
+		return "", err
+	}
+	if h.ready() {
+		h.Query.QueryResults = append(h.Query.QueryResults, qrs)
+	}
+	return h.Query.ActiveQueryStatus, nil
+}
+
+// Results waits for the query to finish, polling while it runs, and returns every page.
+func (h *QueryHandle) Results(ctx context.Context) ([]QueryResultStruct, error) {
+	release, err := h.client.acquireQuery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if n := len(h.Query.QueryResults); n == 0 {
+		err = h.client.checkQuery(ctx, &h.Query)
+	} else if h.Query.hasMore(h.Query.QueryResults[n-1]) && !h.Query.reachedMaxRows() {
+		err = h.client.execute(ctx, &h.Query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Query.QueryResults, nil
+}
@@ -0,0 +1,58 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_SubmitAndAttach(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		httpmock.NewStringResponder(200, `{"queryId":"abc-123","status":"Running","message":null,"data":null}`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/execute/abc-123/result", server),
+		httpmock.NewStringResponder(200, `{"queryId":"abc-123","status":"Finished","message":null,"data":{"columns":["a"],"rows":[{"a":1},{"a":2}],"hasNext":false,"hasPrevious":false}}`))
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	h, err := c.Submit(context.Background(), "SELECT a FROM `db`.`t` WHERE a > ?", 0)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if h.Id() != "abc-123" || h.Query.ActiveQueryStatus != StatusRunning {
+		t.Fatalf("Unexpected handle after Submit: %+v", h.Query)
+	}
+
+	other := NewClient(server, viper.GetString("CONDUIT_TOKEN")).Attach(h.Id())
+	status, err := other.Status(context.Background())
+	if err != nil || status != StatusFinished {
+		t.Fatalf("Actual status %v (%v), Expected Finished", status, err)
+	}
+	results, err := other.Results(context.Background())
+	if err != nil {
+		t.Fatalf("Results failed: %v", err)
+	}
+	if len(results) != 1 || len(results[0].ParsedRows) != 2 {
+		t.Errorf("Expected one page of two rows, got %+v", results)
+	}
+	if calls := httpmock.GetCallCountInfo()[fmt.Sprintf("GET https://%v/api/query/execute/abc-123/result", server)]; calls != 1 {
+		t.Errorf("Results fetched the page again: %v calls", calls)
+	}
+}
+
+func TestQueryHandle_Cancel(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=abc-123", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+	h := NewClient(server, viper.GetString("CONDUIT_TOKEN")).Attach("abc-123")
+	h.Query.ActiveQueryStatus = StatusRunning
+	if result, err := h.Cancel(context.Background()); err != nil || result.Outcome != CancelConfirmed {
+		t.Errorf("Cancel should have succeeded: %+v (%v)", result, err)
+	}
+}
@@ -0,0 +1,199 @@
+package conduit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Run these with make bench; testdata/bench_baseline.txt holds earlier numbers to compare with.
+
+// benchSize is the shape of a synthetic page.
+type benchSize struct {
+	rows, columns int
+}
+
+// benchSizes go up to a full page of a wide table.
+var benchSizes = []benchSize{
+	{10, 10},
+	{100, 20},
+	{1000, 20},
+	{1000, 200},
+}
+
+func (s benchSize) String() string {
+	return fmt.Sprintf("%vx%v", s.rows, s.columns)
+}
+
+// syntheticPage is a page of a query result with columns c000, c001... of, in turn, an
+// integer, a decimal, a string, and a string that is null every other row.
+func syntheticPage(rows, columns int, hasNext bool) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"queryId":"bench","status":"Finished","message":null,"data":{"columns":[`)
+	for c := 0; c < columns; c++ {
+		if c > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `"c%03d"`, c)
+	}
+	b.WriteString(`],"rows":[`)
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('{')
+		for c := 0; c < columns; c++ {
+			if c > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `"c%03d":`, c)
+			switch c % 4 {
+			case 0:
+				fmt.Fprintf(&b, "%d", r*columns+c)
+			case 1:
+				fmt.Fprintf(&b, "%d.%02d", r, c%100)
+			case 2:
+				fmt.Fprintf(&b, `"row %d, column %d"`, r, c)
+			default:
+				if r%2 == 0 {
+					b.WriteString("null")
+				} else {
+					fmt.Fprintf(&b, `"%v"`, strings.Repeat("x", c%32))
+				}
+			}
+		}
+		b.WriteByte('}')
+	}
+	fmt.Fprintf(&b, `],"hasNext":%v,"hasPrevious":false}}`, hasNext)
+	return b.Bytes()
+}
+
+// syntheticRowType is a struct to scan syntheticPage rows into, with a field per column.
+func syntheticRowType(columns int) reflect.Type {
+	kinds := []reflect.Type{reflect.TypeOf(int64(0)), reflect.TypeOf(float64(0)), reflect.TypeOf(""), reflect.TypeOf((*string)(nil))}
+	fields := make([]reflect.StructField, columns)
+	for c := range fields {
+		fields[c] = reflect.StructField{
+			Name: fmt.Sprintf("C%03d", c),
+			Type: kinds[c%4],
+			Tag:  reflect.StructTag(fmt.Sprintf(`conduit:"c%03d"`, c)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+func BenchmarkUnmarshalJsonToQueryResult(b *testing.B) {
+	for _, size := range benchSizes {
+		payload := string(syntheticPage(size.rows, size.columns, false))
+		b.Run(size.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(payload)))
+			for i := 0; i < b.N; i++ {
+				if _, err := UnmarshalJsonToQueryResult(payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStreamQueryResult(b *testing.B) {
+	discard := func(map[string]interface{}) error { return nil }
+	for _, size := range benchSizes {
+		payload := syntheticPage(size.rows, size.columns, false)
+		for _, keep := range []bool{true, false} {
+			name := size.String() + "/keep"
+			var row func(map[string]interface{}) error
+			if !keep {
+				name, row = size.String()+"/discard", discard
+			}
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(payload)))
+				for i := 0; i < b.N; i++ {
+					if _, err := StreamQueryResult(bytes.NewReader(payload), row); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkScanRows(b *testing.B) {
+	for _, size := range benchSizes {
+		qrs, err := UnmarshalJsonToQueryResult(string(syntheticPage(size.rows, size.columns, false)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		sliceType := reflect.SliceOf(syntheticRowType(size.columns))
+		b.Run(size.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				dst := reflect.New(sliceType).Interface()
+				if err := ScanRows(qrs.ParsedRows, dst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkExecuteQuery runs a query of three pages against a local server standing in
+// for Conduit, so it counts the requests, paging and decoding together.
+func BenchmarkExecuteQuery(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	const pages = 3
+	for _, size := range benchSizes {
+		for _, encoding := range []string{"identity", "gzip"} {
+			var bodies [pages][]byte
+			for p := range bodies {
+				bodies[p] = syntheticPage(size.rows, size.columns, p < pages-1)
+				if encoding == "gzip" {
+					var z bytes.Buffer
+					w := gzip.NewWriter(&z)
+					w.Write(bodies[p])
+					w.Close()
+					bodies[p] = z.Bytes()
+				}
+			}
+			var posts int64
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ioutil.ReadAll(req.Body)
+				w.Header().Set("Content-Type", "application/json")
+				if encoding != "identity" {
+					w.Header().Set("Content-Encoding", encoding)
+				}
+				w.Write(bodies[(atomic.AddInt64(&posts, 1)-1)%pages])
+			}))
+			c := NewClient(server.Listener.Addr().String(), "bench")
+			c.HTTPClient = server.Client()
+			if encoding != "identity" {
+				c.Compression = []string{encoding}
+			}
+			b.Run(size.String()+"/"+encoding, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := c.ExecuteQuery(context.Background(), "SELECT * FROM bench"); err != nil {
+						b.Fatal(err)
+					}
+					if n := len(c.Query.QueryResults); n != pages {
+						b.Fatalf("Actual: %v pages\n=====\nExpected: %v", n, pages)
+					}
+				}
+			})
+			server.Close()
+		}
+	}
+}
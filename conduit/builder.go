@@ -0,0 +1,436 @@
+package conduit
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuoteIdentifier wraps a database, table or column name in backticks, doubling any backticks inside it.
+func QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// QuoteTable returns the `database`.`table` form Conduit expects.
+func QuoteTable(database, table string) string {
+	return QuoteIdentifier(database) + "." + QuoteIdentifier(table)
+}
+
+// QuoteValue renders v as a standard SQL literal. Strings are single-quoted with any
+// single quotes doubled, and backslashes are left as they are. Times are written in
+// UTC. Slices render as a parenthesised list for IN, and can't be empty.
+func QuoteValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return quoteString(t), nil
+	case []byte:
+		return "X'" + hex.EncodeToString(t) + "'", nil
+	case bool:
+		if t {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case time.Time:
+		// In UTC, as a TIMESTAMP literal has no zone and the source would read it in its own.
+		return "TIMESTAMP '" + t.UTC().Format("2006-01-02 15:04:05.999999") + "'", nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return QuoteValue(rv.Elem().Interface())
+	case reflect.String:
+		return quoteString(rv.String()), nil
+	case reflect.Bool:
+		return QuoteValue(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("cannot use %v as a SQL literal", f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, rv.Type().Bits()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return "", fmt.Errorf("cannot use an empty %T as a SQL list", v)
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			item, err := QuoteValue(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return "(" + strings.Join(items, ", ") + ")", nil
+	}
+	return "", fmt.Errorf("cannot use %T as a SQL literal", v)
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+type tableRef struct {
+	database, table, alias string
+}
+
+func (t tableRef) String() string {
+	s := QuoteTable(t.database, t.table)
+	if t.alias != "" {
+		s += " AS " + QuoteIdentifier(t.alias)
+	}
+	return s
+}
+
+type joinClause struct {
+	kind        string
+	ref         tableRef
+	left, right string
+}
+
+type whereClause struct {
+	column, op string
+	value      interface{}
+	raw        string
+	args       []interface{}
+}
+
+type orderTerm struct {
+	column string
+	desc   bool
+}
+
+// SelectBuilder assembles a SELECT statement for ExecuteQuery. Columns are written
+// as name, alias.name or *, and are quoted on the way out.
+//
+//	sqlString, err := conduitclient.Select("f.TAIL_NUMBER", "a.airport_name").
+//		From("oracle_flights", "PDBADMIN___FLIGHTS").As("f").
+//		Join("sql_synapse_flights", "TransStats___dimAirports", "a", "f.ORIGIN", "a.code").
+//		Where("f.ORIGIN", "=", "SEA").
+//		OrderBy("f.TAIL_NUMBER").
+//		Limit(100).
+//		Build()
+type SelectBuilder struct {
+	columns []string
+	from    tableRef
+	joins   []joinClause
+	where   []whereClause
+	orderBy []orderTerm
+	limit   int
+	schemas []*TableSchemaStruct
+	err     error
+}
+
+// Select starts a query returning columns, or every column when none are given.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// From sets the table the query reads from.
+func (b *SelectBuilder) From(database, table string) *SelectBuilder {
+	b.from = tableRef{database: database, table: table}
+	return b
+}
+
+// As gives the From table an alias that columns can be qualified with.
+func (b *SelectBuilder) As(alias string) *SelectBuilder {
+	b.from.alias = alias
+	return b
+}
+
+// Join adds an INNER JOIN on leftColumn = rightColumn. The table may live in another Conduit database.
+func (b *SelectBuilder) Join(database, table, alias, leftColumn, rightColumn string) *SelectBuilder {
+	return b.join("INNER JOIN", database, table, alias, leftColumn, rightColumn)
+}
+
+// LeftJoin adds a LEFT JOIN on leftColumn = rightColumn.
+func (b *SelectBuilder) LeftJoin(database, table, alias, leftColumn, rightColumn string) *SelectBuilder {
+	return b.join("LEFT JOIN", database, table, alias, leftColumn, rightColumn)
+}
+
+func (b *SelectBuilder) join(kind, database, table, alias, left, right string) *SelectBuilder {
+	b.joins = append(b.joins, joinClause{
+		kind:  kind,
+		ref:   tableRef{database: database, table: table, alias: alias},
+		left:  left,
+		right: right,
+	})
+	return b
+}
+
+var whereOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"LIKE": true, "NOT LIKE": true, "IN": true, "NOT IN": true, "IS NULL": true, "IS NOT NULL": true,
+}
+
+// Where adds a condition comparing column to value, which is rendered as a literal.
+// Conditions are ANDed. Use IN or NOT IN with a slice, and IS NULL or IS NOT NULL with a nil value.
+func (b *SelectBuilder) Where(column, op string, value interface{}) *SelectBuilder {
+	op = strings.ToUpper(strings.TrimSpace(op))
+	if !whereOperators[op] && b.err == nil {
+		b.err = fmt.Errorf("unsupported operator %q", op)
+	}
+	b.where = append(b.where, whereClause{column: column, op: op, value: value})
+	return b
+}
+
+// WhereRaw adds a SQL fragment as a condition, binding args to its placeholders with Bind.
+// Identifiers in the fragment are used as written.
+func (b *SelectBuilder) WhereRaw(fragment string, args ...interface{}) *SelectBuilder {
+	b.where = append(b.where, whereClause{raw: fragment, args: args})
+	return b
+}
+
+// OrderBy sorts ascending by each column.
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	for _, c := range columns {
+		b.orderBy = append(b.orderBy, orderTerm{column: c})
+	}
+	return b
+}
+
+// OrderByDesc sorts descending by each column.
+func (b *SelectBuilder) OrderByDesc(columns ...string) *SelectBuilder {
+	for _, c := range columns {
+		b.orderBy = append(b.orderBy, orderTerm{column: c, desc: true})
+	}
+	return b
+}
+
+// Limit caps the number of rows returned. Zero means no limit.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	if n < 0 && b.err == nil {
+		b.err = fmt.Errorf("limit must not be negative, got %v", n)
+	}
+	b.limit = n
+	return b
+}
+
+// WithSchemas makes Build check every table and column against these schemas.
+func (b *SelectBuilder) WithSchemas(schemas ...*TableSchemaStruct) *SelectBuilder {
+	b.schemas = append(b.schemas, schemas...)
+	return b
+}
+
+// BuildQuery fetches the schema of every table the builder uses with FetchTableSchema,
+// then builds it with those names checked.
+func (c *ConduitClient) BuildQuery(ctx context.Context, b *SelectBuilder) (string, error) {
+	for _, ref := range b.tables() {
+		schema, err := c.FetchTableSchema(ctx, ref.database, ref.table)
+		if err != nil {
+			return "", fmt.Errorf("unknown table %v: %v", QuoteTable(ref.database, ref.table), err)
+		}
+		b.WithSchemas(schema)
+	}
+	return b.Build()
+}
+
+func (b *SelectBuilder) tables() []tableRef {
+	refs := []tableRef{b.from}
+	for _, j := range b.joins {
+		refs = append(refs, j.ref)
+	}
+	return refs
+}
+
+// Build renders the statement.
+func (b *SelectBuilder) Build() (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	if b.from.database == "" || b.from.table == "" {
+		return "", fmt.Errorf("a query needs a From database and table")
+	}
+	if b.schemas != nil {
+		if err := b.validateTables(); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		sb.WriteString("*")
+	}
+	for i, c := range b.columns {
+		col, err := b.column(c)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(col)
+	}
+	sb.WriteString(" FROM " + b.from.String())
+	for _, j := range b.joins {
+		left, err := b.column(j.left)
+		if err != nil {
+			return "", err
+		}
+		right, err := b.column(j.right)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, " %v %v ON %v = %v", j.kind, j.ref, left, right)
+	}
+	for i, w := range b.where {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		cond, err := b.condition(w)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(cond)
+	}
+	for i, o := range b.orderBy {
+		col, err := b.column(o.column)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			sb.WriteString(" ORDER BY ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(col)
+		if o.desc {
+			sb.WriteString(" DESC")
+		}
+	}
+	if b.limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", b.limit)
+	}
+	return sb.String(), nil
+}
+
+func (b *SelectBuilder) condition(w whereClause) (string, error) {
+	if w.raw != "" {
+		bound, err := Bind(w.raw, w.args...)
+		if err != nil {
+			return "", err
+		}
+		return "(" + bound + ")", nil
+	}
+	col, err := b.column(w.column)
+	if err != nil {
+		return "", err
+	}
+	if w.op == "IS NULL" || w.op == "IS NOT NULL" {
+		return col + " " + w.op, nil
+	}
+	if w.value == nil {
+		return "", fmt.Errorf("%v %v needs a value, use IS NULL to match NULLs", w.column, w.op)
+	}
+	isList := false
+	if rv := reflect.ValueOf(w.value); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		_, isBytes := w.value.([]byte)
+		isList = !isBytes
+	}
+	if (w.op == "IN" || w.op == "NOT IN") != isList {
+		return "", fmt.Errorf("%v %v needs a slice value exactly when using IN or NOT IN", w.column, w.op)
+	}
+	if isList && reflect.ValueOf(w.value).Len() == 0 {
+		// Nothing is IN an empty list, and everything is NOT IN it.
+		if w.op == "IN" {
+			return "1=0", nil
+		}
+		return "1=1", nil
+	}
+	literal, err := QuoteValue(w.value)
+	if err != nil {
+		return "", fmt.Errorf("%v: %v", w.column, err)
+	}
+	return col + " " + w.op + " " + literal, nil
+}
+
+// column quotes a column reference, checking it against the schemas when there are any.
+func (b *SelectBuilder) column(ref string) (string, error) {
+	qualifier, name := "", ref
+	if i := strings.LastIndex(ref, "."); i >= 0 {
+		qualifier, name = ref[:i], ref[i+1:]
+	}
+	if name == "" {
+		return "", fmt.Errorf("empty column name in %q", ref)
+	}
+	if b.schemas != nil {
+		if err := b.validateColumn(qualifier, name); err != nil {
+			return "", err
+		}
+	}
+	quoted := "*"
+	if name != "*" {
+		quoted = QuoteIdentifier(name)
+	}
+	if qualifier != "" {
+		quoted = QuoteIdentifier(qualifier) + "." + quoted
+	}
+	return quoted, nil
+}
+
+func (b *SelectBuilder) schemaFor(ref tableRef) *TableSchemaStruct {
+	for _, s := range b.schemas {
+		if s != nil && s.Database == ref.database && s.Table == ref.table {
+			return s
+		}
+	}
+	return nil
+}
+
+func (b *SelectBuilder) validateTables() error {
+	for _, ref := range b.tables() {
+		if s := b.schemaFor(ref); s == nil || len(s.Columns) == 0 {
+			return fmt.Errorf("table %v not found in Conduit schemas", QuoteTable(ref.database, ref.table))
+		}
+	}
+	return nil
+}
+
+func (b *SelectBuilder) validateColumn(qualifier, name string) error {
+	var candidates []tableRef
+	for _, ref := range b.tables() {
+		if qualifier == "" || qualifier == ref.alias || (ref.alias == "" && qualifier == ref.table) {
+			candidates = append(candidates, ref)
+		}
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("%q does not name a table in the query", qualifier)
+	}
+	if name == "*" {
+		return nil
+	}
+	found := 0
+	for _, ref := range candidates {
+		if s := b.schemaFor(ref); s != nil {
+			for _, c := range s.Columns {
+				if c.Name == name {
+					found++
+					break
+				}
+			}
+		}
+	}
+	switch {
+	case found == 0:
+		return fmt.Errorf("column %q not found", strings.TrimPrefix(qualifier+"."+name, "."))
+	case found > 1:
+		return fmt.Errorf("column %q is ambiguous, qualify it with a table alias", name)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func flightSchemas() []*TableSchemaStruct {
+	return []*TableSchemaStruct{
+		{Database: "oracle_flights", Table: "PDBADMIN___FLIGHTS", Columns: []ColumnStruct{
+			{Name: "TAIL_NUMBER"}, {Name: "ORIGIN"}, {Name: "DEP_TIME"},
+		}},
+		{Database: "sql_synapse_flights", Table: "TransStats___dimAirports", Columns: []ColumnStruct{
+			{Name: "code"}, {Name: "airport_name"},
+		}},
+	}
+}
+
+func TestSelectBuilder_Build(t *testing.T) {
+	sqlString, err := Select("f.TAIL_NUMBER", "a.airport_name").
+		From("oracle_flights", "PDBADMIN___FLIGHTS").As("f").
+		Join("sql_synapse_flights", "TransStats___dimAirports", "a", "f.ORIGIN", "a.code").
+		Where("f.ORIGIN", "in", []string{"SEA", "PDX"}).
+		Where("f.DEP_TIME", ">=", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)).
+		WhereRaw("a.airport_name LIKE ?", "O'Hare%").
+		OrderByDesc("f.DEP_TIME").
+		Limit(100).
+		WithSchemas(flightSchemas()...).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	expected := "SELECT `f`.`TAIL_NUMBER`, `a`.`airport_name` FROM `oracle_flights`.`PDBADMIN___FLIGHTS` AS `f`" +
+		" INNER JOIN `sql_synapse_flights`.`TransStats___dimAirports` AS `a` ON `f`.`ORIGIN` = `a`.`code`" +
+		" WHERE `f`.`ORIGIN` IN ('SEA', 'PDX') AND `f`.`DEP_TIME` >= TIMESTAMP '2020-01-02 03:04:05'" +
+		" AND (a.airport_name LIKE 'O''Hare%') ORDER BY `f`.`DEP_TIME` DESC LIMIT 100"
+	if sqlString != expected {
+		t.Errorf("Actual: \n%s\n=====\nExpected:\n%s", sqlString, expected)
+	}
+}
+
+func TestSelectBuilder_Validation(t *testing.T) {
+	cases := map[string]*SelectBuilder{
+		"not found":     Select("NOPE").From("oracle_flights", "PDBADMIN___FLIGHTS"),
+		"no table":      Select().From("oracle_flights", "MISSING"),
+		"bad alias":     Select("x.ORIGIN").From("oracle_flights", "PDBADMIN___FLIGHTS").As("f"),
+		"bad operator":  Select().From("oracle_flights", "PDBADMIN___FLIGHTS").Where("ORIGIN", "; DROP", 1),
+		"in needs list": Select().From("oracle_flights", "PDBADMIN___FLIGHTS").Where("ORIGIN", "IN", "SEA"),
+	}
+	for name, b := range cases {
+		if _, err := b.WithSchemas(flightSchemas()...).Build(); err == nil {
+			t.Errorf("%v: expected a validation error", name)
+		}
+	}
+}
+
+func TestSelectBuilder_EmptyList(t *testing.T) {
+	sqlString, err := Select("ORIGIN").From("oracle_flights", "PDBADMIN___FLIGHTS").
+		Where("ORIGIN", "IN", []string{}).
+		Where("TAIL_NUMBER", "NOT IN", []string{}).
+		Build()
+	expected := "SELECT `ORIGIN` FROM `oracle_flights`.`PDBADMIN___FLIGHTS` WHERE 1=0 AND 1=1"
+	if err != nil || sqlString != expected {
+		t.Errorf("Actual: %v (%v)\n=====\nExpected: %v", sqlString, err, expected)
+	}
+}
+
+func TestBuildQuery_UnknownTable(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/metadata/databases/oracle_flights/tables/FLIGTHS/schema", viper.GetString("CONDUIT_SERVER")),
+		httpmock.NewStringResponder(404, `{"message":"not found"}`))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	_, err := c.BuildQuery(context.Background(), Select().From("oracle_flights", "FLIGTHS"))
+	if err == nil || !strings.Contains(err.Error(), "unknown table `oracle_flights`.`FLIGTHS`") {
+		t.Errorf("Actual: %v\n=====\nExpected: unknown table `oracle_flights`.`FLIGTHS`", err)
+	}
+}
+
+func TestQuoteValue(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "NULL"},
+		{`it's' OR 1=1 --`, `'it''s'' OR 1=1 --'`},
+		{`C:\temp`, `'C:\temp'`},
+		{42, "42"},
+		{-1.5, "-1.5"},
+		{true, "TRUE"},
+		{[]int{1, 2}, "(1, 2)"},
+		{[]byte{0xca, 0xfe}, "X'cafe'"},
+		{time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("PST", -8*3600)), "TIMESTAMP '2020-01-02 11:04:05'"},
+	}
+	for _, c := range cases {
+		actual, err := QuoteValue(c.value)
+		if err != nil || actual != c.expected {
+			t.Errorf("QuoteValue(%#v) Actual: %v (%v) Expected: %v", c.value, actual, err, c.expected)
+		}
+	}
+	if _, err := QuoteValue([]string{}); err == nil {
+		t.Errorf("An empty list has no SQL literal")
+	}
+	if QuoteIdentifier("we`ird") != "`we``ird`" {
+		t.Errorf("Backticks inside identifiers must be doubled: %v", QuoteIdentifier("we`ird"))
+	}
+}
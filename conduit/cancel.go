@@ -0,0 +1,156 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// CancelOutcome says how a cancellation ended.
+type CancelOutcome int
+
+const (
+	// CancelFailed means Conduit never confirmed the cancel, so the query may still be running.
+	CancelFailed CancelOutcome = iota
+	// CancelConfirmed means Conduit reported the query as cancelled.
+	CancelConfirmed
+	// CancelNotNeeded means there was no active query, or it had already reached a terminal
+	// state. A Finished query with pages still to fetch is cancelled.
+	CancelNotNeeded
+)
+
+func (o CancelOutcome) String() string {
+	switch o {
+	case CancelConfirmed:
+		return "confirmed"
+	case CancelNotNeeded:
+		return "not needed"
+	}
+	return "failed"
+}
+
+// CancelResult reports what Cancel did.
+type CancelResult struct {
+	QueryId  string
+	Outcome  CancelOutcome
+	Attempts int
+	Status   string // last status seen for the query
+}
+
+// MaxCancelAttempts bounds how many times Cancel asks Conduit, when the context doesn't stop it first.
+var MaxCancelAttempts = 8
+
+// cancelRetryDelay is the first wait between attempts. It doubles each time, up to cancelMaxDelay.
+var (
+	cancelRetryDelay = 500 * time.Millisecond
+	cancelMaxDelay   = 10 * time.Second
+)
+
+// DetachedCancelTimeout bounds the cancels the client makes on its own, when a query
+// times out, stops at MaxRows or its Rows are closed, as no caller's context limits them.
+var DetachedCancelTimeout = 10 * time.Second
+
+// Cancel cancels the client's active query. See QueryHandle.Cancel.
+func (c *ConduitClient) Cancel(ctx context.Context) (CancelResult, error) {
+	return c.cancelWithRetry(ctx, &c.Query)
+}
+
+// Cancel asks Conduit to cancel the query until it confirms, or until the query turns out
+// to have finished, backing off between attempts. A CancelFailed outcome comes with the
+// last error, which is ctx.Err() when the context ran out first.
+func (h *QueryHandle) Cancel(ctx context.Context) (CancelResult, error) {
+	return h.client.cancelWithRetry(ctx, &h.Query)
+}
+
+// cancelDetached cancels q without a caller's context, giving up after DetachedCancelTimeout.
+func (c *ConduitClient) cancelDetached(q *QueryStruct) (CancelResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DetachedCancelTimeout)
+	defer cancel()
+	return c.cancelWithRetry(ctx, q)
+}
+
+func (c *ConduitClient) cancelWithRetry(ctx context.Context, q *QueryStruct) (CancelResult, error) {
+	result := CancelResult{QueryId: q.ActiveQueryId, Status: q.ActiveQueryStatus}
+	if q.ActiveQueryId == "" || (q.ActiveQueryStatus == StatusFinished && !q.pagesLeft()) {
+		log.Printf("There isn't any Active Query to attempt to cancel...")
+		result.Outcome = CancelNotNeeded
+		return result, nil
+	}
+	type CancelStruct struct {
+		IsCancelled bool `json:"isCancelled"`
+	}
+	delay := cancelRetryDelay
+	var lastErr error
+	for result.Attempts < MaxCancelAttempts {
+		if result.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > cancelMaxDelay {
+				delay = cancelMaxDelay
+			}
+			e := q.event(EventRetrying)
+			e.Attempt, e.Err = result.Attempts+1, lastErr
+			c.emit(e)
+		}
+		result.Attempts++
+		log.Printf("Canceling QueryId %v (attempt %v)....", q.ActiveQueryId, result.Attempts)
+		cancelled := new(CancelStruct)
+		err := c.getFrom(ctx, q.Server, fmt.Sprintf("/query/cancel?queryId=%v", q.ActiveQueryId), cancelled)
+		if err == nil && cancelled.IsCancelled {
+			log.Printf("QueryId %v successfully canceled.", q.ActiveQueryId)
+			result.Outcome = CancelConfirmed
+			q.ActiveQueryStatus = "Cancelled"
+			c.trackQuery(q.ActiveQueryId, q.Server, false)
+			result.Status = q.ActiveQueryStatus
+			e := q.event(EventCancelled)
+			e.Attempt = result.Attempts
+			c.emit(e)
+			return result, nil
+		}
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("Conduit did not confirm cancelling QueryId %v", q.ActiveQueryId)
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if status, err := c.queryStatus(ctx, q); err == nil {
+			result.Status = status
+			if status != StatusRunning && status != StatusResultsReady {
+				log.Printf("QueryId %v is already %v, nothing to cancel.", q.ActiveQueryId, status)
+				c.trackQuery(q.ActiveQueryId, q.Server, false)
+				result.Outcome = CancelNotNeeded
+				return result, nil
+			}
+		}
+	}
+	return result, lastErr
+}
+
+// pagesLeft reports whether Conduit may still hold pages of q, as a Finished query does
+// until its last page is fetched. It's true when no whole page has been read yet.
+func (q *QueryStruct) pagesLeft() bool {
+	n := len(q.QueryResults)
+	return n == 0 || q.hasMore(q.QueryResults[n-1])
+}
+
+// queryStatus fetches the query's current status. It asks for a page of one row, and
+// drops whatever rows come back as they're decoded.
+func (c *ConduitClient) queryStatus(ctx context.Context, q *QueryStruct) (string, error) {
+	resp, err := c.send(ctx, q.Server, "GET", fmt.Sprintf("/query/execute/%v/result?pageSize=1", q.ActiveQueryId))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	probe := QueryStruct{onRow: func([]string, map[string]interface{}) error { return nil }}
+	if _, err := c.decodeQueryResponse(&probe, resp); err != nil {
+		return "", err
+	}
+	q.ActiveQueryStatus = probe.ActiveQueryStatus
+	return probe.ActiveQueryStatus, nil
+}
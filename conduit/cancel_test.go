@@ -0,0 +1,126 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func setupCancelMocks(t *testing.T, cancelResponses []string, status string) *QueryHandle {
+	cancelRetryDelay = time.Millisecond
+	t.Cleanup(func() { cancelRetryDelay = 500 * time.Millisecond })
+	httpmock.Activate()
+	t.Cleanup(httpmock.DeactivateAndReset)
+	server := viper.GetString("CONDUIT_SERVER")
+	calls := 0
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server),
+		func(req *http.Request) (*http.Response, error) {
+			body := cancelResponses[len(cancelResponses)-1]
+			if calls < len(cancelResponses) {
+				body = cancelResponses[calls]
+			}
+			calls++
+			if body == "" {
+				return nil, fmt.Errorf("connection reset")
+			}
+			return httpmock.NewStringResponse(200, body), nil
+		})
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/execute/q1/result?pageSize=1", server),
+		httpmock.NewStringResponder(200, fmt.Sprintf(`{"queryId":"q1","status":%q,"data":null}`, status)))
+	h := NewClient(server, viper.GetString("CONDUIT_TOKEN")).Attach("q1")
+	h.Query.ActiveQueryStatus = StatusRunning
+	return h
+}
+
+func TestQueryHandle_CancelRetriesUntilConfirmed(t *testing.T) {
+	h := setupCancelMocks(t, []string{"", `{"isCancelled":false}`, `{"isCancelled":true}`}, StatusRunning)
+	result, err := h.Cancel(context.Background())
+	if err != nil || result.Outcome != CancelConfirmed || result.Attempts != 3 {
+		t.Errorf("Actual: %+v (%v), Expected confirmed after 3 attempts", result, err)
+	}
+}
+
+func TestQueryHandle_CancelStopsOnTerminalStatus(t *testing.T) {
+	h := setupCancelMocks(t, []string{`{"isCancelled":false}`}, StatusFinished)
+	result, err := h.Cancel(context.Background())
+	if err != nil || result.Outcome != CancelNotNeeded || result.Status != StatusFinished {
+		t.Errorf("Actual: %+v (%v), Expected not needed because the query finished", result, err)
+	}
+}
+
+func TestQueryHandle_CancelGivesUp(t *testing.T) {
+	h := setupCancelMocks(t, []string{`{"isCancelled":false}`}, StatusRunning)
+	result, err := h.Cancel(context.Background())
+	if err == nil || result.Outcome != CancelFailed || result.Attempts != MaxCancelAttempts {
+		t.Errorf("Actual: %+v (%v), Expected failure after %v attempts", result, err, MaxCancelAttempts)
+	}
+}
+
+func TestConduitClient_CancelQueryIsBounded(t *testing.T) {
+	h := setupCancelMocks(t, []string{`{"isCancelled":false}`}, StatusRunning)
+	cancelRetryDelay, DetachedCancelTimeout = time.Hour, 50*time.Millisecond
+	defer func() { DetachedCancelTimeout = 10 * time.Second }()
+	h.client.Query = h.Query
+	done := make(chan bool)
+	go func() { done <- h.client.CancelQuery() }()
+	select {
+	case confirmed := <-done:
+		if confirmed {
+			t.Errorf("Actual: confirmed\n=====\nExpected: not confirmed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("CancelQuery still waiting after DetachedCancelTimeout")
+	}
+}
+
+func TestConduitClient_MaxRowsCancelsFinishedQueryWithMorePages(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		httpmock.NewStringResponder(200, `{"queryId":"q1","status":"Finished","message":null,"data":{"columns":["a"],"rows":[{"a":1},{"a":2}],"hasNext":true,"hasPrevious":false}}`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	if err := c.ExecuteQueryWithOptions(context.Background(), QueryOptions{PageSize: 2, MaxRows: 2}, "SELECT a FROM t"); err != nil {
+		t.Fatalf("ExecuteQueryWithOptions failed: %v", err)
+	}
+	if calls := httpmock.GetCallCountInfo()[fmt.Sprintf("GET https://%v/api/query/cancel?queryId=q1", server)]; calls != 1 {
+		t.Errorf("Actual: %v cancels\n=====\nExpected: 1", calls)
+	}
+	if c.Query.ActiveQueryStatus != "Cancelled" {
+		t.Errorf("Actual: %v\n=====\nExpected: Cancelled", c.Query.ActiveQueryStatus)
+	}
+
+	httpmock.ZeroCallCounters()
+	rows, err := c.QueryRows(context.Background(), "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("QueryRows failed: %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("Expected a first row: %v", rows.Err())
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if calls := httpmock.GetCallCountInfo()[fmt.Sprintf("GET https://%v/api/query/cancel?queryId=q1", server)]; calls != 1 {
+		t.Errorf("Actual: %v cancels after Rows.Close\n=====\nExpected: 1", calls)
+	}
+}
+
+func TestQueryStruct_PagesLeft(t *testing.T) {
+	q := QueryStruct{ActiveQueryId: "q1", ActiveQueryStatus: StatusFinished}
+	if !q.pagesLeft() {
+		t.Errorf("Expected pages left before any page was read")
+	}
+	q.QueryResults = []QueryResultStruct{{}}
+	if q.pagesLeft() {
+		t.Errorf("Expected no pages left after a last page")
+	}
+}
@@ -1,16 +1,21 @@
 package conduit
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 )
+// DatabasesStruct, TablesStruct and TableSchemaStruct marshal to JSON and YAML in the shape
+// Conduit's metadata endpoints return them.
 type DatabasesStruct struct {
-	Databases []string
+	Databases []string `json:"databases" yaml:"databases"`
 }
 func (s DatabasesStruct) Print() {
 	for _, v := range s.Databases {
@@ -18,13 +23,13 @@ func (s DatabasesStruct) Print() {
 	}
 }
 type TableStruct struct {
-	Table string
-	Database string
-	Schema string
-	TableType string
+	Table string `json:"table" yaml:"table"`
+	Database string `json:"database" yaml:"database"`
+	Schema string `json:"schema" yaml:"schema"`
+	TableType string `json:"tableType" yaml:"tableType"`
 }
 type TablesStruct struct {
-	Tables []TableStruct
+	Tables []TableStruct `json:"tables" yaml:"tables"`
 }
 func (s TablesStruct) Print() {
 	for _, v := range s.Tables {
@@ -32,21 +37,34 @@ func (s TablesStruct) Print() {
 			v.Table, v.Database, v.Schema, v.TableType)
 	}
 }
+// ColumnStruct describes one column of a table. LengthOpt and ScaleOpt are nil when
+// Conduit sends null; for DECIMAL and NUMERIC columns they are the precision and scale.
 type ColumnStruct struct {
-	Name string
-	ColType string
-	LengthOpt string
-	ScaleOpt string
-	SqlType int
+	Name string `json:"name" yaml:"name"`
+	ColType string `json:"colType" yaml:"colType"`
+	LengthOpt *int `json:"lengthOpt" yaml:"lengthOpt"`
+	ScaleOpt *int `json:"scaleOpt" yaml:"scaleOpt"`
+	SqlType int `json:"sqlType" yaml:"sqlType"`
+	// Nullable, PrimaryKey and OrdinalPosition are only set by servers that report them.
+	// A nil Nullable means it isn't known, and OrdinalPosition counts from 1.
+	Nullable *bool `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	PrimaryKey bool `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	OrdinalPosition int `json:"ordinalPosition,omitempty" yaml:"ordinalPosition,omitempty"`
 }
 func (c ColumnStruct) Print() {
 	fmt.Printf("Column name: %v, colType: %v, lengthOpt: %v, scaleOpt: %v, sqlType: %v\n",
-		c.Name, c.ColType, c.LengthOpt, c.ScaleOpt, c.SqlType)
+		c.Name, c.ColType, optString(c.LengthOpt), optString(c.ScaleOpt), c.SqlType)
+}
+func optString(opt *int) string {
+	if opt == nil {
+		return "null"
+	}
+	return strconv.Itoa(*opt)
 }
 type TableSchemaStruct struct {
-	Database string
-	Table string
-	Columns []ColumnStruct
+	Database string `json:"database,omitempty" yaml:"database,omitempty"`
+	Table string `json:"table,omitempty" yaml:"table,omitempty"`
+	Columns []ColumnStruct `json:"columns" yaml:"columns"`
 }
 func (c TableSchemaStruct) Print() {
 	for _, v := range c.Columns {
@@ -56,9 +74,50 @@ func (c TableSchemaStruct) Print() {
 type ConduitClient struct {
 	ConduitServer string
 	ConduitToken string
+	// TokenSource, when set, is asked for the token on every request instead of using
+	// ConduitToken. Wrap slow sources with CachedToken.
+	TokenSource TokenSource
+	// Servers are more Conduit servers to share the load with ConduitServer and take over when it is down.
+	Servers []string
+	// Options are the defaults for ExecuteQuery, Submit, Attach and extracts.
+	Options QueryOptions
+	// RequestsPerSecond limits the calls made to Conduit, across every goroutine using the
+	// client, allowing Burst at once. Zero means no limit. Set them before first use.
+	RequestsPerSecond float64
+	Burst int
+	// MaxActiveQueries caps how many queries the client drives at once, whether executing,
+	// submitting or waiting on results; callers beyond it wait. Zero means no cap.
+	MaxActiveQueries int
+	// HTTPClient sends the requests, after any middleware added with Use. Nil means http.DefaultClient.
+	HTTPClient *http.Client
+	// StrictDecoding makes responses with fields this SDK doesn't know about an error,
+	// to catch API changes early. Responses that aren't JSON are always an error.
+	StrictDecoding bool
+	// MaxResponseSize caps how many bytes of one response are read; more is an error
+	// wrapping ErrResponseTooLarge. Zero means DefaultMaxResponseSize, and negative no limit.
+	MaxResponseSize int64
+	// Compression lists the Content-Encodings to ask for, most preferred first, such as
+	// "gzip" or "deflate", or others added with RegisterDecompressor. Responses are
+	// decompressed as they are read. Nil leaves it to the HTTP transport.
+	Compression []string
 	Query QueryStruct
+
+	mu sync.Mutex
+	activeQueries map[string]string // queries started here that may still hold resources on Conduit, to their server
+	closed bool
+	hooks []EventHook
+	health map[string]serverState
+	nextServer int
+	limiter *tokenBucket
+	querySlots chan struct{}
+	middleware []Middleware
 }
 
+// QueryResultStruct is one page of a query's results. It marshals to JSON and YAML in the
+// shape Conduit returns it, with the rows and columns held once under "data", and fills
+// ParsedColumns and ParsedRows when unmarshaled. Pages the client fetches are decoded as they
+// stream in, and keep their rows only in ParsedRows: RawData.Rows is nil. Numbers in
+// ParsedRows are float64s; QueryStruct.Scan still fills int64 fields from larger integers exactly.
 type QueryResultStruct struct {
 	QueryId string `json:"queryId"`
 	Status string `json:"status"`
@@ -69,200 +128,354 @@ type QueryResultStruct struct {
 		Columns *json.RawMessage `json:"columns"`
 		Rows *json.RawMessage `json:"rows"`
 	} `json:"data"`
-	ParsedColumns []string
-	ParsedRows []map[string]interface{}
+	ParsedColumns []string `json:"-" yaml:"-"`
+	ParsedRows []map[string]interface{} `json:"-" yaml:"-"`
+	exact map[int]exactNumbers // integers in ParsedRows that lost digits as float64s, by row
 }
-func UnmarshalJsonToQueryResult(payload string) QueryResultStruct {
+// UnmarshalJsonToQueryResult decodes one result page. Payloads that aren't a result give a
+// *DecodeError quoting the part that couldn't be read.
+func UnmarshalJsonToQueryResult(payload string) (QueryResultStruct, error) {
 	qrs := QueryResultStruct{}
-	json.Unmarshal([]byte(payload), &qrs)
-	if qrs.RawData.Columns != nil {
-		//column processing
-		json.Unmarshal(*qrs.RawData.Columns, &qrs.ParsedColumns)
-	}
-	if qrs.RawData.Columns != nil {
-		//row processing
-		var rows []json.RawMessage
-		json.Unmarshal(*qrs.RawData.Rows, &rows)
-		for _, value := range rows {
-			var objmap map[string]interface{}
-			json.Unmarshal(value, &objmap)
-			qrs.ParsedRows = append(qrs.ParsedRows, objmap)
-		}
-	}
-	return qrs
+	err := decodeJSON([]byte(payload), &qrs, false)
+	return qrs, err
 }
 type QueryStruct struct {
 	SQLString string
-	PageSize int
-	Timeout int
+	Options QueryOptions
 	StartTime time.Time
+	Server string // the server that accepted the query, which its later calls must go to
 	ActiveQueryId string
 	ActiveQueryStatus string
 	QueryResults []QueryResultStruct
+	polls int
+	onRow func(columns []string, row map[string]interface{}) error // takes each row as it's decoded, in place of ParsedRows
+	onPage func(QueryResultStruct) error // takes each page's rows in place of QueryResults
+	pageRows int // rows in the last page read, whether kept or handed to onRow
 }
+// NewQuery treats a pageSize of 0, or one above MaxPageSize, as MaxPageSize (1000), and a
+// timeout of 0 seconds as 30.
+//
+// Deprecated: use NewQueryWithOptions, which reports invalid values instead of adjusting them.
 func NewQuery(sqlString string, pageSize, timeout int) QueryStruct {
-	MaxPageSize := 1000
-	q := QueryStruct{
-		SQLString:     sqlString,
-		Timeout:       timeout,
-	}
-	if pageSize < MaxPageSize {
-		q.PageSize = pageSize
-	} else {
-		q.PageSize = MaxPageSize
+	opts := QueryOptions{Timeout: time.Duration(timeout) * time.Second}
+	if pageSize > 0 && pageSize < MaxPageSize {
+		opts.PageSize = pageSize
 	}
-	if timeout == 0 {
-		q.Timeout = 30
-	}
-	return q
+	return QueryStruct{SQLString: sqlString, Options: opts.withDefaults()}
 }
 func (c *ConduitClient) TimedOut() bool {
-	if c.Query.StartTime.IsZero() {
-		c.Query.StartTime = time.Now()
+	return c.Query.TimedOut()
+}
+// TimedOut starts the query's clock on the first call, and reports whether Timeout has passed since.
+func (q *QueryStruct) TimedOut() bool {
+	if q.StartTime.IsZero() {
+		q.StartTime = time.Now()
 		return false
 	}
 	t := time.Now()
-	elapsed := t.Sub(c.Query.StartTime)
-	if int(elapsed.Seconds()) >= c.Query.Timeout {
+	elapsed := t.Sub(q.StartTime)
+	if elapsed >= q.Options.Timeout {
 		fmt.Print("Timed out...")
 		return true
 	}
 	return false
 }
+// CancelQuery cancels the active query, retrying as Cancel does for up to DetachedCancelTimeout,
+// and reports whether Conduit confirmed it.
 func (c *ConduitClient) CancelQuery() bool {
-	if c.Query.ActiveQueryId == "" ||
-		c.Query.ActiveQueryStatus == "Finished"{
-		log.Printf("There isn't any Active Query to attempt to cancel...")
-		return false
-	}
-	log.Printf("Canceling QueryId %v....", c.Query.ActiveQueryId)
-	type CancelStruct struct {
-		IsCancelled bool `json:"isCancelled"`
-	}
-	cancelled := new(CancelStruct)
-
-	err := c.GetOnTheWire(fmt.Sprintf("/query/cancel?queryId=%v", c.Query.ActiveQueryId), cancelled)
-	fmt.Println(cancelled)
-	if err != nil {
-		log.Fatalf("Error on the wire: %v", err.Error())
-	}
-	if !cancelled.IsCancelled {
-		//time.Sleep(2 * time.Second)
-		//c.CancelQuery()	//recursion fun...may need to have max attempts
-		return false
-	} else {
-		log.Printf("QueryId %v successfully canceled.", c.Query.ActiveQueryId)
-		return true
-	}
+	return c.cancelQuery(&c.Query)
 }
-func (c *ConduitClient) Execute() error {
-	if c.TimedOut() {
-		c.CancelQuery()
-		return nil
-	}
-	httpClient := &http.Client{}
-	formedUrl := fmt.Sprintf("https://%v/api/query/execute", c.ConduitServer)
-	reqBody, err := json.Marshal(map[string]interface{}{
-		"queryId": nil,
-		"query": c.Query.SQLString,
-		"pageSize": c.Query.PageSize,
-	})
+func (c *ConduitClient) cancelQuery(q *QueryStruct) bool {
+	result, err := c.cancelDetached(q)
 	if err != nil {
-		log.Printf("Could not marshal body for POSTing query: %v", err.Error())
-		return err
+		log.Printf("Could not cancel QueryId %v: %v", q.ActiveQueryId, err)
 	}
-	req, err := http.NewRequest("POST", formedUrl, bytes.NewBuffer(reqBody))
+	return result.Outcome == CancelConfirmed
+}
+func (c *ConduitClient) Execute(ctx context.Context) error {
+	return c.run(ctx, &c.Query)
+}
+// run executes q once one of MaxActiveQueries is free.
+func (c *ConduitClient) run(ctx context.Context, q *QueryStruct) error {
+	release, err := c.acquireQuery(ctx)
 	if err != nil {
-		log.Printf("Error forming URL: %s", err.Error() )
+		c.emit(q.failed(err))
 		return err
 	}
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.ConduitToken))
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := httpClient.Do(req)
+	defer release()
+	return c.execute(ctx, q)
+}
+// execute posts q, or asks for its next page once it has an ActiveQueryId, and follows
+// the result until every page is in q.QueryResults. It only touches q, so queries on
+// separate QueryStructs can run concurrently on one client.
+func (c *ConduitClient) execute(ctx context.Context, q *QueryStruct) error {
+	if q.TimedOut() {
+		c.emit(q.event(EventTimedOut))
+		c.cancelQuery(q)
+		return ErrQueryTimedOut
+	}
+	resp, err := c.postQuery(ctx, q)
 	if err != nil {
-		log.Printf("Error doing request: %s", err.Error())
+		c.emit(q.failed(err))
 		return err
 	}
 	defer resp.Body.Close()
 
-	err = c.ProcessQueryResult(resp)
+	err = c.processQueryResult(ctx, q, resp)
 	if err != nil {
 		return err
 	}
 	return nil
 }
-func (c *ConduitClient) ProcessQueryResult(response *http.Response) error {
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(response.Body)
-	respString := buf.String()
-	qrs := UnmarshalJsonToQueryResult(respString)
-	if response.StatusCode != 200 {
-		errstring := fmt.Sprintf("Status Code %v returned with message %v", response.StatusCode, qrs.Message)
-		log.Printf(errstring)
-		return errors.New(errstring)
+func (c *ConduitClient) postQuery(ctx context.Context, q *QueryStruct) (*http.Response, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+	var queryId interface{}
+	if q.ActiveQueryId != "" {
+		queryId = q.ActiveQueryId
+	}
+	body := map[string]interface{}{
+		"queryId": queryId,
+		"query": q.SQLString,
+		"pageSize": q.Options.withDefaults().PageSize,
+	}
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Could not marshal body for POSTing query: %v", err.Error())
+		return nil, err
+	}
+	if q.ActiveQueryId != "" && q.Server != "" {
+		// Later pages only exist on the server running the query.
+		return c.do(ctx, q.Server, "POST", "/query/execute", reqBody)
+	}
+	resp, server, err := c.doAny(ctx, "POST", "/query/execute", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	q.Server = server
+	return resp, nil
+}
+func (c *ConduitClient) ProcessQueryResult(ctx context.Context, response *http.Response) error {
+	return c.processQueryResult(ctx, &c.Query, response)
+}
+func (c *ConduitClient) processQueryResult(ctx context.Context, q *QueryStruct, response *http.Response) error {
+	qrs, err := c.readQueryResult(q, response)
+	if err != nil {
+		return err
 	}
-	c.Query.ActiveQueryId = qrs.QueryId
-	c.Query.ActiveQueryStatus = qrs.Status
 	if qrs.Status == "Finished" || qrs.Status == "ResultsReady" {
-		c.Query.QueryResults = append(c.Query.QueryResults, qrs)
-		if qrs.RawData.HasNext {
+		if q.onPage != nil {
+			if err := q.onPage(qrs); err != nil {
+				c.emit(q.failed(err))
+				return err
+			}
+			qrs.ParsedRows = nil
+		}
+		q.QueryResults = append(q.QueryResults, qrs)
+		if q.reachedMaxRows() {
+			log.Printf("Query has the %v rows asked for, not fetching further pages", q.Options.MaxRows)
+			if q.hasMore(qrs) {
+				c.stopEarly(q)
+			}
+			return nil
+		}
+		if q.hasMore(qrs) {
 			log.Printf("Query is finished, but has more, so paging...")
-			c.Query.Print()
-			c.Execute()
+			q.Print()
+			return c.execute(ctx, q)
 		}
 	} else if qrs.Status == "Running" {
-		time.Sleep(2 * time.Second)
+		q.polls++
+		select {
+		case <-ctx.Done():
+			c.emit(q.failed(ctx.Err()))
+			return ctx.Err()
+		case <-time.After(q.Options.withDefaults().PollStrategy.Delay(q.polls)):
+		}
 		log.Printf("Query is Running, need to poll for completion...")
-		c.CheckQuery()
+		return c.checkQuery(ctx, q)
 	} else {
 
-		return errors.New(fmt.Sprintf("Query isn't running or finished. Status: %v. Query: %v", qrs.Status, c.Query))
+		err = errors.New(fmt.Sprintf("Query isn't running or finished. Status: %v. Query: %v", qrs.Status, *q))
+		c.emit(q.failed(err))
+		return err
 	}
 	return nil
 
 }
-func (c *ConduitClient) CheckQuery() error {
-	if c.TimedOut() {
-		c.CancelQuery()
-		return nil
+// readQueryResult decodes one response for q, records its query ID and status, tracks
+// the query for Close while it is running or has pages left, and emits its events. A
+// query that would be tracked after Close has begun is cancelled instead.
+func (c *ConduitClient) readQueryResult(q *QueryStruct, response *http.Response) (QueryResultStruct, error) {
+	prevId, prevStatus := q.ActiveQueryId, q.ActiveQueryStatus
+	qrs, err := c.decodeQueryResponse(q, response)
+	if err != nil {
+		if err != errRowsClosed {
+			c.emit(q.failed(err))
+		}
+		return qrs, err
+	}
+	ready := qrs.Status == "Finished" || qrs.Status == "ResultsReady"
+	if err := c.trackQuery(qrs.QueryId, q.Server, qrs.Status == "Running" || (ready && q.hasMore(qrs))); err != nil {
+		// The query started while Close was cancelling the others, so cancel it here.
+		c.cancelDetached(q)
+		c.emit(q.failed(err))
+		return qrs, err
+	}
+	if prevId == "" && qrs.QueryId != "" {
+		c.emit(q.event(EventSubmitted))
+	}
+	if qrs.Status != prevStatus {
+		c.emit(q.event(EventStatusChanged))
+	}
+	if ready {
+		e := q.event(EventPageReceived)
+		e.Rows = q.pageRows
+		c.emit(e)
+	}
+	return qrs, nil
+}
+// decodeQueryResponse decodes one response for q, handing its rows to q.onRow when it is set.
+func (c *ConduitClient) decodeQueryResponse(q *QueryStruct, response *http.Response) (QueryResultStruct, error) {
+	q.pageRows = 0
+	var row func([]string, map[string]interface{}) error
+	if q.onRow != nil {
+		row = func(columns []string, r map[string]interface{}) error {
+			q.pageRows++
+			return q.onRow(columns, r)
+		}
+	}
+	qrs, err := decodeQueryPage(response.Body, c.StrictDecoding, row)
+	q.pageRows += len(qrs.ParsedRows)
+	err = describeResponse(err, response, "")
+	if response.StatusCode != 200 {
+		message := qrs.Message
+		if e, ok := err.(*DecodeError); ok {
+			// Not a Conduit error, maybe a proxy's: show what it said instead.
+			message = e.Snippet
+		}
+		errstring := fmt.Sprintf("Status Code %v returned with message %v", response.StatusCode, message)
+		log.Printf(errstring)
+		return qrs, errors.New(errstring)
 	}
-	url := fmt.Sprintf("https://%v/api/query/execute/%v/result", c.ConduitServer, c.Query.ActiveQueryId)
-	log.Printf(fmt.Sprintf("Getting URL: %v", url))
-	httpClient := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		log.Printf("Error forming URL: %s", err.Error() )
-		return err
+		if q.pageRows > 0 && qrs.QueryId != "" {
+			// Rows were handed on before the page broke off, so the query is running
+			// and may need cancelling.
+			q.ActiveQueryId, q.ActiveQueryStatus = qrs.QueryId, qrs.Status
+		}
+		return qrs, err
 	}
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.ConduitToken))
-	resp, err := httpClient.Do(req)
+	q.ActiveQueryId = qrs.QueryId
+	q.ActiveQueryStatus = qrs.Status
+	return qrs, nil
+}
+func (c *ConduitClient) CheckQuery(ctx context.Context) error {
+	return c.checkQuery(ctx, &c.Query)
+}
+func (c *ConduitClient) checkQuery(ctx context.Context, q *QueryStruct) error {
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+	if q.TimedOut() {
+		c.emit(q.event(EventTimedOut))
+		c.cancelQuery(q)
+		return ErrQueryTimedOut
+	}
+	pollCtx := ctx
+	if q.Options.PollTimeout > 0 {
+		var cancel context.CancelFunc
+		pollCtx, cancel = context.WithTimeout(ctx, q.Options.PollTimeout)
+		defer cancel()
+	}
+	resp, err := c.getQueryResult(pollCtx, q)
 	if err != nil {
-		log.Printf("Error doing request: %s", err.Error())
+		c.emit(q.failed(err))
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		errstring := fmt.Sprintf("Status Code %v returned", resp.StatusCode)
-		log.Printf(errstring)
-		return errors.New(errstring)
-	}
-	err = c.ProcessQueryResult(resp)
+	err = c.processQueryResult(ctx, q, resp)
 	if err != nil {
 		return err
 	}
 	return nil
 
 }
+func (c *ConduitClient) getQueryResult(ctx context.Context, q *QueryStruct) (*http.Response, error) {
+	endpoint := fmt.Sprintf("/query/execute/%v/result", q.ActiveQueryId)
+	log.Printf(fmt.Sprintf("Getting URL: %v on %v", endpoint, q.Server))
+	resp, err := c.send(ctx, q.Server, "GET", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		errstring := fmt.Sprintf("Status Code %v returned", resp.StatusCode)
+		log.Printf(errstring)
+		return nil, errors.New(errstring)
+	}
+	return resp, nil
+}
 func (q *QueryStruct) Print() {
-	log.Printf("Query object is using pagesize %v, with timeout %v, start time: %v",
-		q.PageSize, q.Timeout, q.StartTime)
+	log.Printf("Query %v is using pagesize %v, with timeout %v, start time: %v",
+		q.Options.Label, q.Options.PageSize, q.Options.Timeout, q.StartTime)
+}
+// hasMore reports whether Conduit has another page after qrs.
+func (q *QueryStruct) hasMore(qrs QueryResultStruct) bool {
+	return qrs.RawData.HasNext
+}
+// reachedMaxRows trims the last page to MaxRows and reports whether there are enough rows.
+func (q *QueryStruct) reachedMaxRows() bool {
+	if q.Options.MaxRows == 0 {
+		return false
+	}
+	total := 0
+	for i, v := range q.QueryResults {
+		total += len(v.ParsedRows)
+		if total >= q.Options.MaxRows {
+			last := &q.QueryResults[i]
+			last.ParsedRows = last.ParsedRows[:len(last.ParsedRows)-(total-q.Options.MaxRows)]
+			q.QueryResults = q.QueryResults[:i+1]
+			return true
+		}
+	}
+	return false
+}
+// stopEarly cancels q on Conduit once no more of its pages are wanted, so it doesn't run
+// to completion. It doesn't use the caller's context, which may be what stopped the query.
+func (c *ConduitClient) stopEarly(q *QueryStruct) {
+	if result, err := c.cancelDetached(q); err != nil {
+		log.Printf("Could not cancel QueryId %v after stopping early: %v", q.ActiveQueryId, err)
+	} else {
+		log.Printf("Stopped QueryId %v early, cancel %v", q.ActiveQueryId, result.Outcome)
+	}
 }
+// failed starts an EventFailed for err.
+func (q *QueryStruct) failed(err error) QueryEvent {
+	e := q.event(EventFailed)
+	e.Err = err
+	return e
+}
+// NewClient makes a client for conduitServer. An empty server or token is taken from the
+// credentials stored by the driver's login command, for the profile in CONDUIT_PROFILE.
+// The stored token is only taken for the server it was stored with.
 func NewClient(conduitServer, conduitToken string) *ConduitClient {
 	if len(conduitServer) == 0 || len(conduitToken) == 0 {
-		log.Fatal("You need to set CONDUIT_SERVER and CONDUIT_TOKEN somewhere")
+		creds, ok, err := LoadCredentials(os.Getenv("CONDUIT_PROFILE"))
+		if err != nil {
+			log.Printf("Could not read stored credentials: %v", err)
+		}
+		if ok && conduitServer == "" {
+			conduitServer = creds.Server
+		}
+		if ok && conduitToken == "" {
+			conduitToken = creds.tokenFor(conduitServer)
+		}
+		if len(conduitServer) == 0 || len(conduitToken) == 0 {
+			log.Printf("No CONDUIT_SERVER and CONDUIT_TOKEN set, and no stored credentials: requests will fail until they are set, or after running login")
+		}
 	}
 	return &ConduitClient{
 		ConduitServer: conduitServer,
@@ -270,21 +483,18 @@ func NewClient(conduitServer, conduitToken string) *ConduitClient {
 	}
 }
 func (c *ConduitClient) Print() {
-	log.Printf("Conduit Client uses server: %v, with Token: <redacted>", c.ConduitServer)
+	log.Printf("Conduit Client uses servers: %v, with Token: <redacted>", c.servers())
 }
 func (c *ConduitClient) GetOnTheWire(endpoint string, target interface{}) (err error){
-	formedUrl := fmt.Sprintf("https://%s/api%s", c.ConduitServer, endpoint)
-	httpClient := &http.Client{}
-	req, err := http.NewRequest("GET", formedUrl, nil)
-	if err != nil {
-		log.Printf("Error forming URL: %s", err.Error() )
-		return err
-	}
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.ConduitToken))
-	resp, err := httpClient.Do(req)
+	return c.getOnTheWire(context.Background(), endpoint, target)
+}
+func (c *ConduitClient) getOnTheWire(ctx context.Context, endpoint string, target interface{}) (err error){
+	return c.getFrom(ctx, "", endpoint, target)
+}
+// getFrom decodes a GET of endpoint on server, or on any healthy server when it's empty.
+func (c *ConduitClient) getFrom(ctx context.Context, server, endpoint string, target interface{}) (err error){
+	resp, err := c.send(ctx, server, "GET", endpoint)
 	if err != nil {
-		log.Printf("Error doing request: %s", err.Error())
 		return err
 	}
 
@@ -296,19 +506,22 @@ func (c *ConduitClient) GetOnTheWire(endpoint string, target interface{}) (err e
 		return errors.New(errstring)
 	}
 
-	//buf := new(bytes.Buffer)
-	//buf.ReadFrom(resp.Body)
-	//respString := buf.String()
-	//fmt.Println(respString)
-	json.NewDecoder(resp.Body).Decode(target)
-	return nil
+	return decodeResponse(resp, endpoint, target, c.StrictDecoding)
+}
+// send makes a bodiless request to server, or to any healthy server when it's empty.
+func (c *ConduitClient) send(ctx context.Context, server, method, endpoint string) (*http.Response, error) {
+	if server != "" {
+		return c.do(ctx, server, method, endpoint, nil)
+	}
+	resp, _, err := c.doAny(ctx, method, endpoint, nil)
+	return resp, err
 }
 func (c *ConduitClient) GetDatabases() *DatabasesStruct {
 	curlstring := "curl -X GET \"https://$CONDUIT_SERVER/api/metadata/databases\" -H  \"accept: application/json\" -H \"Authorization: Bearer $CONDUIT_TOKEN\""
 	databases := new(DatabasesStruct)
 	err := c.GetOnTheWire("/metadata/databases", databases)
 	if err != nil {
-		log.Fatalf("Error calling GetOnTheWire... equivalent CURL: %s", curlstring)
+		log.Fatalf("Error calling GetOnTheWire: %v... equivalent CURL: %s", err, curlstring)
 	}
 	return databases
 }
@@ -318,30 +531,51 @@ func (c *ConduitClient) GetTables(database string) *TablesStruct {
 	tables := new(TablesStruct)
 	err := c.GetOnTheWire(fmt.Sprintf("/metadata/databases/%s/tables",database), tables)
 	if err != nil {
-		log.Fatalf("Error calling GetOnTheWire... equivalent CURL: %s", curlstring)
+		log.Fatalf("Error calling GetOnTheWire: %v... equivalent CURL: %s", err, curlstring)
 	}
 	return tables
 }
 func (c *ConduitClient) GetTableSchema(database, table string) *TableSchemaStruct {
 	curlstring := fmt.Sprintf("curl -X GET \"https://$CONDUIT_SERVER/api/metadata/databases/%s/tables/%s/schema\" -H  \"accept: application/json\" -H \"Authorization: Bearer $CONDUIT_TOKEN\"", database, table)
+	tableSchema, err := c.FetchTableSchema(context.Background(), database, table)
+	if err != nil {
+		log.Fatalf("Error calling GetOnTheWire: %v... equivalent CURL: %s", err, curlstring)
+	}
+	return tableSchema
+}
+// FetchTableSchema is GetTableSchema returning its error rather than exiting.
+func (c *ConduitClient) FetchTableSchema(ctx context.Context, database, table string) (*TableSchemaStruct, error) {
 	tableSchema := new(TableSchemaStruct)
 	tableSchema.Database = database
 	tableSchema.Table = table
-	err := c.GetOnTheWire(fmt.Sprintf("/metadata/databases/%s/tables/%s/schema", database, table), tableSchema)
+	err := c.getOnTheWire(ctx, fmt.Sprintf("/metadata/databases/%s/tables/%s/schema", database, table), tableSchema)
 	if err != nil {
-		log.Fatalf("Error calling GetOnTheWire... equivalent CURL: %s", curlstring)
+		return nil, err
 	}
-	return tableSchema
+	return tableSchema, nil
 }
 
-func (c *ConduitClient) ExecuteQuery(sqlString string, windowSize, timeout int) error {
+func (c *ConduitClient) ExecuteQuery(ctx context.Context, sqlString string, args ...interface{}) error {
 	/*
 	Several activities occur here:
 	1. Query is executed quickly, with no pagination.
 	2. Query is _started_, returns with a Running status, to be polled until finished.
 	3. Query returns paginated (either in case #1 or #2 above); must slide the window, re-execute query
-	4. Timeout occurs during 1, 2, or 3; at which time a cancel is issued.
+	4. Timeout occurs during 1, 2, or 3; at which time a cancel is issued, and ErrQueryTimedOut returned.
+	Any args are bound to the ? or :name placeholders in sqlString first, see Bind.
+	The client's Options apply; use ExecuteQueryWithOptions to set them per query.
 	*/
-	c.Query = NewQuery(sqlString, windowSize, timeout)
-	return c.Execute()
+	return c.ExecuteQueryWithOptions(ctx, c.Options, sqlString, args...)
+}
+// ExecuteQueryWithOptions runs sqlString as ExecuteQuery does, with opts in place of the client's Options.
+func (c *ConduitClient) ExecuteQueryWithOptions(ctx context.Context, opts QueryOptions, sqlString string, args ...interface{}) error {
+	boundSql, err := Bind(sqlString, args...)
+	if err != nil {
+		return err
+	}
+	c.Query, err = NewQueryWithOptions(boundSql, opts)
+	if err != nil {
+		return err
+	}
+	return c.Execute(ctx)
 }
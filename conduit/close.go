@@ -0,0 +1,95 @@
+package conduit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrClientClosed is returned for queries started after Close.
+var ErrClientClosed = errors.New("conduit client is closed")
+
+// trackQuery records whether a query may still be running, for Close. It returns
+// ErrClientClosed rather than tracking a running query once Close has begun, as Close
+// has already taken the queries it will cancel.
+func (c *ConduitClient) trackQuery(queryId, server string, active bool) error {
+	if queryId == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !active {
+		delete(c.activeQueries, queryId)
+		return nil
+	}
+	if c.closed {
+		return ErrClientClosed
+	}
+	if c.activeQueries == nil {
+		c.activeQueries = map[string]string{}
+	}
+	c.activeQueries[queryId] = server
+	return nil
+}
+
+func (c *ConduitClient) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// ActiveQueryIds lists the queries started through this client that may still be running on Conduit.
+func (c *ConduitClient) ActiveQueryIds() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id := range c.activeQueries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Close stops the client taking new queries, then cancels every query it started that
+// may still be running, all at once, waiting until they're done or ctx ends. The error
+// lists the queries that couldn't be confirmed as cancelled.
+func (c *ConduitClient) Close(ctx context.Context) error {
+	// One lock for all of it, so each query is taken with the server it was tracked on.
+	c.mu.Lock()
+	c.closed = true
+	servers := map[string]string{}
+	var ids []string
+	for id, server := range c.activeQueries {
+		servers[id] = server
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+	sort.Strings(ids)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			q := QueryStruct{ActiveQueryId: id, ActiveQueryStatus: StatusRunning, Server: servers[id]}
+			result, err := c.cancelWithRetry(ctx, &q)
+			if result.Outcome == CancelFailed {
+				mu.Lock()
+				failed = append(failed, fmt.Sprintf("%v (%v)", id, err))
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("could not cancel %v of %v queries: %v", len(failed), len(ids), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_Close(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		httpmock.NewStringResponder(200, `{"queryId":"q1","status":"Running","data":null}`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	if _, err := c.Submit(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if ids := c.ActiveQueryIds(); len(ids) != 1 || ids[0] != "q1" {
+		t.Fatalf("Actual active queries: %v, Expected [q1]", ids)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if ids := c.ActiveQueryIds(); len(ids) != 0 {
+		t.Errorf("Queries still tracked after Close: %v", ids)
+	}
+	if err := c.ExecuteQuery(context.Background(), "SELECT 1"); err != ErrClientClosed {
+		t.Errorf("Actual: %v, Expected: %v", err, ErrClientClosed)
+	}
+}
+
+func TestConduitClient_TracksOnlyUnfinishedQueries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		httpmock.NewStringResponder(200, `{"queryId":"q2","status":"Finished","data":{"columns":[],"rows":[],"hasNext":false}}`))
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	if err := c.ExecuteQuery(context.Background(), "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if ids := c.ActiveQueryIds(); len(ids) != 0 {
+		t.Errorf("Finished query still tracked: %v", ids)
+	}
+}
+
+func TestConduitClient_CloseCancelsLateQueries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		func(req *http.Request) (*http.Response, error) {
+			// Close runs before the first response arrives, so it has nothing to cancel yet.
+			if err := c.Close(context.Background()); err != nil {
+				t.Errorf("Close failed: %v", err)
+			}
+			return httpmock.NewStringResponse(200, `{"queryId":"q1","status":"Running","data":null}`), nil
+		})
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+
+	if _, err := c.Submit(context.Background(), "SELECT 1"); err != ErrClientClosed {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", err, ErrClientClosed)
+	}
+	if calls := httpmock.GetCallCountInfo()[fmt.Sprintf("GET https://%v/api/query/cancel?queryId=q1", server)]; calls != 1 {
+		t.Errorf("Actual: %v cancels\n=====\nExpected: 1", calls)
+	}
+	if ids := c.ActiveQueryIds(); len(ids) != 0 {
+		t.Errorf("Query tracked after Close: %v", ids)
+	}
+}
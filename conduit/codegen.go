@@ -0,0 +1,199 @@
+package conduit
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateOptions controls the Go source written by GenerateStructs.
+type GenerateOptions struct {
+	Package      string
+	Constants    bool          // emit a table constant and one constant per column name
+	QueryHelpers bool          // emit a Query<Table> helper that scans rows into the struct
+	Nullable     bool          // use pointer fields for types that can't hold a NULL themselves, unless the column is NOT NULL
+	Types        *TypeRegistry // defaults to DefaultTypeRegistry
+}
+
+// GenerateStructs writes one Go struct per table schema to w. Fields get their
+// type from opts.Types and carry a `conduit` tag with the column name.
+func GenerateStructs(w io.Writer, opts GenerateOptions, schemas ...*TableSchemaStruct) error {
+	if opts.Package == "" {
+		opts.Package = "models"
+	}
+	if opts.Types == nil {
+		opts.Types = DefaultTypeRegistry
+	}
+	sorted := make([]*TableSchemaStruct, len(schemas))
+	copy(sorted, schemas)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Database != sorted[j].Database {
+			return sorted[i].Database < sorted[j].Database
+		}
+		return sorted[i].Table < sorted[j].Table
+	})
+
+	var body bytes.Buffer
+	imports := map[string]bool{}
+	if opts.QueryHelpers {
+		imports[`conduit "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"`] = true
+		imports[`"context"`] = true
+	}
+	// Every top-level name declared so far, so a table called FooTable doesn't clash
+	// with the FooTable constant of a table called Foo.
+	declared := map[string]bool{}
+	for _, s := range sorted {
+		fieldNames := map[string]bool{}
+		fields := make([]string, len(s.Columns))
+		for i, col := range s.Columns {
+			fields[i] = uniqueName(GoIdentifier(col.Name), fieldNames)
+		}
+		typeName := declareTable(GoIdentifier(s.Table), fields, opts, declared)
+		fmt.Fprintf(&body, "\n// %v mirrors the %v table.\n", typeName, quotedTable(s))
+		fmt.Fprintf(&body, "type %v struct {\n", typeName)
+		var consts bytes.Buffer
+		for i, col := range s.Columns {
+			fieldName := fields[i]
+			// Columns the server reports as NOT NULL don't need pointers.
+			nullable := opts.Nullable && (col.Nullable == nil || *col.Nullable)
+			goType := goTypeSource(opts.Types.GoType(col, nullable), imports)
+			fmt.Fprintf(&body, "\t%v %v `conduit:%q`", fieldName, goType, col.Name)
+			if col.PrimaryKey {
+				body.WriteString(" // primary key")
+			}
+			body.WriteString("\n")
+			fmt.Fprintf(&consts, "\t%vCol%v = %q\n", typeName, fieldName, col.Name)
+		}
+		body.WriteString("}\n")
+		if opts.Constants {
+			body.WriteString("\nconst (\n")
+			fmt.Fprintf(&body, "\t%vTable = %q\n", typeName, quotedTable(s))
+			body.Write(consts.Bytes())
+			body.WriteString(")\n")
+		}
+		if opts.QueryHelpers {
+			fmt.Fprintf(&body, "\n// Query%v runs sqlString and scans every returned row into a %v.\n", typeName, typeName)
+			fmt.Fprintf(&body, "func Query%v(ctx context.Context, c *conduit.ConduitClient, sqlString string, args ...interface{}) ([]%v, error) {\n", typeName, typeName)
+			body.WriteString("\tif err := c.ExecuteQuery(ctx, sqlString, args...); err != nil {\n\t\treturn nil, err\n\t}\n")
+			fmt.Fprintf(&body, "\tvar rows []%v\n", typeName)
+			body.WriteString("\terr := c.Query.Scan(&rows)\n\treturn rows, err\n}\n")
+		}
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by Conduit-GoSDK generate. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %v\n", opts.Package)
+	if len(imports) > 0 {
+		var list []string
+		for k := range imports {
+			list = append(list, k)
+		}
+		sort.Strings(list)
+		fmt.Fprintf(&src, "\nimport (\n\t%v\n)\n", strings.Join(list, "\n\t"))
+	}
+	src.Write(body.Bytes())
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not parse: %v", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+func quotedTable(s *TableSchemaStruct) string {
+	return fmt.Sprintf("`%v`.`%v`", s.Database, s.Table)
+}
+
+// goTypeSource renders t as Go source, collecting the import paths it needs.
+func goTypeSource(t reflect.Type, imports map[string]bool) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + goTypeSource(t.Elem(), imports)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && t.Elem().PkgPath() == "" {
+			return "[]byte"
+		}
+		return "[]" + goTypeSource(t.Elem(), imports)
+	case reflect.Map:
+		return "map[" + goTypeSource(t.Key(), imports) + "]" + goTypeSource(t.Elem(), imports)
+	case reflect.Interface:
+		if t.NumMethod() == 0 && t.PkgPath() == "" {
+			return "interface{}"
+		}
+	}
+	if t.PkgPath() != "" {
+		imports[strconv.Quote(t.PkgPath())] = true
+	}
+	return t.String()
+}
+
+// GoIdentifier turns a Conduit table or column name into an exported Go identifier,
+// so PDBADMIN___FLIGHTS becomes PdbadminFlights and airport_name becomes AirportName.
+func GoIdentifier(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, p := range parts {
+		runes := []rune(p)
+		if strings.ToUpper(p) == p {
+			runes = []rune(strings.ToLower(p))
+		}
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	id := b.String()
+	if id == "" {
+		return "X"
+	}
+	if unicode.IsDigit([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
+}
+
+// declareTable picks the type name for a table, numbering it when the type or any of
+// the names generated from it is already declared, and declares them all.
+func declareTable(name string, fields []string, opts GenerateOptions, declared map[string]bool) string {
+	for i := 1; ; i++ {
+		candidate := name
+		if i > 1 {
+			candidate = fmt.Sprintf("%v%v", name, i)
+		}
+		names := []string{candidate}
+		if opts.Constants {
+			names = append(names, candidate+"Table")
+			for _, f := range fields {
+				names = append(names, candidate+"Col"+f)
+			}
+		}
+		if opts.QueryHelpers {
+			names = append(names, "Query"+candidate)
+		}
+		clash := false
+		for _, n := range names {
+			clash = clash || declared[n]
+		}
+		if !clash {
+			for _, n := range names {
+				declared[n] = true
+			}
+			return candidate
+		}
+	}
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%v%v", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,96 @@
+package conduit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateStructs(t *testing.T) {
+	schemaJson := `{"columns":[{"name":"airport_name","colType":"nvarchar","lengthOpt":null,"scaleOpt":null,"sqlType":1111},{"name":"code","colType":"int","lengthOpt":null,"scaleOpt":null,"sqlType":4},{"name":"opened","colType":"date","lengthOpt":null,"scaleOpt":null,"sqlType":91}]}`
+	schema := &TableSchemaStruct{Database: "oracle_flights", Table: "PDBADMIN___AIRPORTS"}
+	if err := json.Unmarshal([]byte(schemaJson), schema); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err := GenerateStructs(&out, GenerateOptions{Package: "models", Constants: true, QueryHelpers: true}, schema)
+	if err != nil {
+		t.Fatalf("GenerateStructs failed: %v", err)
+	}
+	src := out.String()
+	for _, expected := range []string{
+		"package models",
+		"type PdbadminAirports struct {",
+		"AirportName string    `conduit:\"airport_name\"`",
+		"Code        int32     `conduit:\"code\"`",
+		"Opened      time.Time `conduit:\"opened\"`",
+		"PdbadminAirportsTable          = \"`oracle_flights`.`PDBADMIN___AIRPORTS`\"",
+		"PdbadminAirportsColAirportName = \"airport_name\"",
+		"func QueryPdbadminAirports(ctx context.Context, c *conduit.ConduitClient",
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("Generated code is missing %q:\n%v", expected, src)
+		}
+	}
+}
+
+func TestGenerateStructs_Nullable(t *testing.T) {
+	schemaJson := `{"columns":[{"name":"id","colType":"int","sqlType":4,"nullable":false,"primaryKey":true},{"name":"code","colType":"int","sqlType":4,"nullable":true},{"name":"opened","colType":"date","sqlType":91}]}`
+	schema := &TableSchemaStruct{Database: "db", Table: "airports"}
+	if err := json.Unmarshal([]byte(schemaJson), schema); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := GenerateStructs(&out, GenerateOptions{Nullable: true}, schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Id     int32      `conduit:\"id\"` // primary key",
+		"Code   *int32     `conduit:\"code\"`",
+		"Opened *time.Time `conduit:\"opened\"`",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Generated code is missing %q:\n%v", expected, out.String())
+		}
+	}
+}
+
+func TestGoIdentifier(t *testing.T) {
+	cases := map[string]string{
+		"PDBADMIN___FLIGHTS":       "PdbadminFlights",
+		"TransStats___dimCarriers": "TransStatsDimCarriers",
+		"airport_name":             "AirportName",
+		"2020_totals":              "X2020Totals",
+		"___":                      "X",
+	}
+	for in, expected := range cases {
+		if actual := GoIdentifier(in); actual != expected {
+			t.Errorf("GoIdentifier(%q) Actual: %v Expected: %v", in, actual, expected)
+		}
+	}
+}
+
+func TestGenerateStructs_NameCollisions(t *testing.T) {
+	schemas := []*TableSchemaStruct{
+		{Database: "db", Table: "foo", Columns: []ColumnStruct{{Name: "id", ColType: "int", SqlType: 4}}},
+		{Database: "db", Table: "foo_table", Columns: []ColumnStruct{{Name: "id", ColType: "int", SqlType: 4}}},
+		{Database: "db", Table: "FOO", Columns: []ColumnStruct{{Name: "id", ColType: "int", SqlType: 4}}},
+	}
+	var out bytes.Buffer
+	if err := GenerateStructs(&out, GenerateOptions{Constants: true, QueryHelpers: true}, schemas...); err != nil {
+		t.Fatalf("GenerateStructs failed: %v", err)
+	}
+	for _, expected := range []string{
+		"type Foo struct {",
+		"FooTable = \"`db`.`FOO`\"",
+		"type Foo2 struct {",
+		"Foo2Table = \"`db`.`foo`\"",
+		"type FooTable2 struct {",
+		"FooTable2Table = \"`db`.`foo_table`\"",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Generated code is missing %q:\n%v", expected, out.String())
+		}
+	}
+}
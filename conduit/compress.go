@@ -0,0 +1,116 @@
+package conduit
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Decompressor reads a response body sent with one Content-Encoding.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var decompressors = struct {
+	sync.RWMutex
+	byEncoding map[string]Decompressor
+}{byEncoding: map[string]Decompressor{
+	"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"deflate": inflate,
+}}
+
+// RegisterDecompressor makes encoding available to ConduitClient.Compression, or replaces
+// its decompressor. gzip and deflate are built in; zstd can be added with a library such
+// as github.com/klauspost/compress/zstd:
+//
+//	conduit.RegisterDecompressor("zstd", func(r io.Reader) (io.ReadCloser, error) {
+//		d, err := zstd.NewReader(r)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return d.IOReadCloser(), nil
+//	})
+func RegisterDecompressor(encoding string, d Decompressor) {
+	decompressors.Lock()
+	defer decompressors.Unlock()
+	decompressors.byEncoding[strings.ToLower(encoding)] = d
+}
+
+func decompressorFor(encoding string) (Decompressor, bool) {
+	decompressors.RLock()
+	defer decompressors.RUnlock()
+	d, ok := decompressors.byEncoding[strings.ToLower(encoding)]
+	return d, ok
+}
+
+// inflate reads deflate bodies, which should be zlib streams but are raw deflate from
+// some servers.
+func inflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint(header[0])<<8|uint(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// acceptEncoding is the Accept-Encoding header asking for c.Compression, most preferred
+// first, or empty when the transport is left to choose.
+func (c *ConduitClient) acceptEncoding() (string, error) {
+	var accept []string
+	for i, encoding := range c.Compression {
+		if _, ok := decompressorFor(encoding); !ok {
+			return "", fmt.Errorf("no decompressor registered for %q, see RegisterDecompressor", encoding)
+		}
+		// Each later encoding gets a lower quality, so the order is the preference.
+		switch {
+		case i == 0:
+			accept = append(accept, encoding)
+		case i < 9:
+			accept = append(accept, fmt.Sprintf("%v;q=0.%d", encoding, 10-i))
+		default:
+			accept = append(accept, encoding+";q=0.1")
+		}
+	}
+	return strings.Join(accept, ", "), nil
+}
+
+// decompress replaces a compressed response body with one that decompresses it as it's read.
+func (c *ConduitClient) decompress(resp *http.Response) error {
+	encoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
+	if len(c.Compression) == 0 || encoding == "" || strings.EqualFold(encoding, "identity") {
+		return nil
+	}
+	d, ok := decompressorFor(encoding)
+	if !ok {
+		return fmt.Errorf("Conduit sent a response with unsupported Content-Encoding %q", encoding)
+	}
+	r, err := d(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading %v response: %v", encoding, err)
+	}
+	resp.Body = &decompressedBody{Reader: r, decompressor: r, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+type decompressedBody struct {
+	io.Reader
+	decompressor io.Closer
+	body         io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	b.decompressor.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,189 @@
+package conduit
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+// compressed returns a responder sending payload with the given Content-Encoding,
+// and records the Accept-Encoding it was asked with.
+func compressed(encoding string, payload string, accepted *string) httpmock.Responder {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&b)
+	case "zlib":
+		w, encoding = zlib.NewWriter(&b), "deflate"
+	case "deflate":
+		w, _ = flate.NewWriter(&b, flate.DefaultCompression)
+	default:
+		w = nopWriteCloser{&b}
+	}
+	w.Write([]byte(payload))
+	w.Close()
+	return func(req *http.Request) (*http.Response, error) {
+		*accepted = req.Header.Get("Accept-Encoding")
+		resp := httpmock.NewBytesResponse(200, b.Bytes())
+		resp.Header.Set("Content-Encoding", encoding)
+		return resp, nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestCompression(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("https://%v/api/metadata/databases", viper.GetString("CONDUIT_SERVER"))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	c.Compression = []string{"gzip", "deflate"}
+	for _, encoding := range []string{"gzip", "zlib", "deflate", "identity"} {
+		var accepted string
+		httpmock.RegisterResponder("GET", url, compressed(encoding, `{"databases":["oracle_flights"]}`, &accepted))
+		var dbs DatabasesStruct
+		if err := c.GetOnTheWire("/metadata/databases", &dbs); err != nil || len(dbs.Databases) != 1 {
+			t.Errorf("%v\nActual: %+v (%v)\n=====\nExpected: one database", encoding, dbs, err)
+		}
+		if accepted != "gzip, deflate;q=0.9" {
+			t.Errorf("Actual: %v\n=====\nExpected: gzip, deflate;q=0.9", accepted)
+		}
+	}
+
+	var accepted string
+	httpmock.RegisterResponder("GET", url, compressed("br", `{"databases":[]}`, &accepted))
+	if err := c.GetOnTheWire("/metadata/databases", &DatabasesStruct{}); err == nil || !strings.Contains(err.Error(), `"br"`) {
+		t.Errorf("Actual: %v\n=====\nExpected: an unsupported Content-Encoding", err)
+	}
+	c.Compression = []string{"zstd"}
+	if err := c.GetOnTheWire("/metadata/databases", &DatabasesStruct{}); err == nil || !strings.Contains(err.Error(), "RegisterDecompressor") {
+		t.Errorf("Actual: %v\n=====\nExpected: no decompressor for zstd", err)
+	}
+}
+
+func TestCompression_Middleware(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var accepted string
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/metadata/databases", viper.GetString("CONDUIT_SERVER")),
+		compressed("gzip", `{"databases":["oracle_flights"]}`, &accepted))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	c.Compression = []string{"gzip"}
+	var seen string
+	c.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			if err != nil {
+				return resp, err
+			}
+			payload, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			seen = resp.Header.Get("Content-Encoding") + string(payload)
+			resp.Body = ioutil.NopCloser(bytes.NewReader(payload))
+			return resp, err
+		}
+	})
+	var dbs DatabasesStruct
+	if err := c.GetOnTheWire("/metadata/databases", &dbs); err != nil || len(dbs.Databases) != 1 {
+		t.Errorf("Actual: %+v (%v)\n=====\nExpected: one database", dbs, err)
+	}
+	if seen != `{"databases":["oracle_flights"]}` {
+		t.Errorf("Actual: %q\n=====\nExpected: middleware to see the decompressed body", seen)
+	}
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	defer func() {
+		decompressors.Lock()
+		delete(decompressors.byEncoding, "upper")
+		decompressors.Unlock()
+	}()
+	// A made-up encoding that upper-cases JSON, undone by lower-casing it.
+	RegisterDecompressor("Upper", func(r io.Reader) (io.ReadCloser, error) {
+		payload, err := ioutil.ReadAll(r)
+		return ioutil.NopCloser(bytes.NewReader(bytes.ToLower(payload))), err
+	})
+	url := fmt.Sprintf("https://%v/api/metadata/databases", viper.GetString("CONDUIT_SERVER"))
+	var accepted string
+	httpmock.RegisterResponder("GET", url, compressed("upper", `{"DATABASES":["ORACLE_FLIGHTS"]}`, &accepted))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	c.Compression = []string{"upper"}
+	var dbs DatabasesStruct
+	if err := c.GetOnTheWire("/metadata/databases", &dbs); err != nil || len(dbs.Databases) != 1 || dbs.Databases[0] != "oracle_flights" {
+		t.Errorf("Actual: %+v (%v)\n=====\nExpected: oracle_flights", dbs, err)
+	}
+}
+
+func TestCompression_Paging(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	registerPages(server)
+	pages := []string{
+		`{"queryId":"q1","status":"ResultsReady","message":null,"data":{"columns":["a"],"rows":[{"a":1},{"a":2}],"hasNext":true,"hasPrevious":false}}`,
+		`{"queryId":"q1","status":"Finished","message":null,"data":{"columns":["a"],"rows":[{"a":3}],"hasNext":false,"hasPrevious":true}}`,
+	}
+	var accepted string
+	posts := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		func(req *http.Request) (*http.Response, error) {
+			posts++
+			return compressed("gzip", pages[posts-1], &accepted)(req)
+		})
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	c.Compression = []string{"gzip"}
+	rows, err := c.QueryRows(context.Background(), "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("QueryRows failed: %v", err)
+	}
+	defer rows.Close()
+	var got []interface{}
+	for rows.Next() {
+		got = append(got, rows.Row()["a"])
+	}
+	if rows.Err() != nil || fmt.Sprint(got) != "[1 2 3]" || accepted != "gzip" {
+		t.Errorf("Actual: %v (%v), Accept-Encoding %v\n=====\nExpected: [1 2 3] asked for with gzip", got, rows.Err(), accepted)
+	}
+}
+
+func TestCompression_MaxResponseSize(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	// Compresses to far less than the limit, but expands past it.
+	page := `{"queryId":"q1","status":"Finished","data":{"columns":["a"],"rows":[{"a":"` + strings.Repeat("x", 100000) + `"}],"hasNext":false}}`
+	url := fmt.Sprintf("https://%v/api/query/execute", viper.GetString("CONDUIT_SERVER"))
+	var accepted string
+	httpmock.RegisterResponder("POST", url, compressed("gzip", page, &accepted))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	c.Compression = []string{"gzip"}
+	c.MaxResponseSize = 10000
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", err, ErrResponseTooLarge)
+	}
+}
@@ -0,0 +1,581 @@
+package conduit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ConfigFileName is the config file LoadConfig looks for in the working directory, then
+// the home directory. Earlier releases looked for ConduitClient.toml.toml, which is still read.
+const ConfigFileName = "ConduitClient.toml"
+
+const legacyConfigFileName = "ConduitClient.toml.toml"
+
+// Config is what a client needs to talk to one Conduit environment.
+type Config struct {
+	Profile string // the profile the settings came from, if any
+	File    string // the config file read, if any
+	Server  string
+	Servers []string
+	// The token comes from the one of these that is set. See TokenSource.
+	Token              string
+	TokenCommand       string // run with sh -c to print the token
+	TokenFile          string // file holding the token, readable only by its owner
+	EncryptedTokenFile string // written by WriteEncryptedToken, decrypted with $CONDUIT_TOKEN_PASSPHRASE
+	KeyringService     string // Secret Service entry, looked up with secret-tool
+	KeyringAccount     string
+	PageSize           int
+	Timeout            time.Duration
+	TLS                TLSConfig
+
+	// Sources says where each setting that was set came from, keyed by its name in the config file.
+	Sources map[string]string
+}
+
+// TLSConfig holds the TLS settings for talking to Conduit.
+type TLSConfig struct {
+	CAFile             string // PEM bundle of extra trusted CAs
+	CertFile           string // client certificate, with KeyFile
+	KeyFile            string
+	KeyPassphrase      string // decrypts KeyFile when it's an encrypted PKCS#8 key
+	ServerName         string
+	InsecureSkipVerify *bool // nil when not set, so a later layer can turn it back off
+}
+
+// SkipVerify reports whether InsecureSkipVerify is set to true.
+func (t TLSConfig) SkipVerify() bool {
+	return t.InsecureSkipVerify != nil && *t.InsecureSkipVerify
+}
+
+// LoadOptions says where LoadConfig looks.
+type LoadOptions struct {
+	// Profile picks a [profiles.<name>] table. When empty, CONDUIT_PROFILE is used, then
+	// the file's default_profile.
+	Profile string
+	// File is the config file to read. When empty, CONDUIT_CONFIG is used, then
+	// ConfigFileName in the working directory, then in the home directory.
+	File string
+	// Flags, when set, are checked for changed --server, --token, --token-command,
+	// --token-file, --page-size and --timeout flags, and the older --CONDUIT_SERVER and --CONDUIT_TOKEN.
+	Flags *pflag.FlagSet
+	// NewProfile lets the profile be one that neither the config file nor the stored
+	// credentials have yet, as when logging in to it for the first time.
+	NewProfile bool
+}
+
+// LoadConfig builds a Config from, lowest precedence first:
+//
+//  1. the top level of the config file (server, token, ... or the older CONDUIT_SERVER, CONDUIT_TOKEN)
+//  2. the selected [profiles.<name>] table
+//  3. environment variables: CONDUIT_SERVER, CONDUIT_SERVERS, CONDUIT_TOKEN,
+//     CONDUIT_TOKEN_COMMAND, CONDUIT_TOKEN_FILE, CONDUIT_ENCRYPTED_TOKEN_FILE,
+//     CONDUIT_KEYRING_SERVICE, CONDUIT_KEYRING_ACCOUNT, CONDUIT_PAGE_SIZE, CONDUIT_TIMEOUT
+//     and CONDUIT_TLS_KEY_PASSPHRASE
+//  4. flags that were set on the command line
+//
+// A server or token still missing after that comes from the credentials stored for the
+// profile by login; the stored token only when the server is the stored one too. A missing config file isn't an error. A profile that is neither in the file
+// nor stored by login is, unless opts.NewProfile is set.
+func LoadConfig(opts LoadOptions) (*Config, error) {
+	cfg := &Config{Sources: map[string]string{}}
+	v, file, err := readConfigFile(opts.File)
+	if err != nil {
+		return nil, err
+	}
+	cfg.File = file
+	if v != nil {
+		top, err := configSection(v)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", file, err)
+		}
+		if top.Server == "" {
+			top.Server = v.GetString("conduit_server")
+		}
+		if top.Token == "" {
+			top.Token = v.GetString("conduit_token")
+		}
+		cfg.merge(top, file)
+	}
+
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv("CONDUIT_PROFILE")
+	}
+	if profile == "" && v != nil {
+		profile = v.GetString("default_profile")
+	}
+	if profile != "" {
+		var section *viper.Viper
+		if v != nil {
+			section = v.Sub("profiles." + profile)
+		}
+		if section != nil {
+			p, err := configSection(section)
+			if err != nil {
+				return nil, fmt.Errorf("profile %v: %v", profile, err)
+			}
+			cfg.merge(p, "profile "+profile)
+		} else if !opts.NewProfile {
+			// A profile made by login has no table in the file, only stored credentials.
+			if _, stored, err := LoadCredentials(profile); err != nil {
+				return nil, err
+			} else if !stored {
+				return nil, fmt.Errorf("no profile %q in %v or in the stored credentials", profile, describeFile(file))
+			}
+		}
+		cfg.Profile = profile
+	}
+
+	env, err := envConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.merge(env, "environment")
+	if opts.Flags != nil {
+		flags, err := flagConfig(opts.Flags)
+		if err != nil {
+			return nil, err
+		}
+		cfg.merge(flags, "flags")
+	}
+	if cfg.Server == "" || !cfg.hasTokenSource() {
+		if err := cfg.mergeStored(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// mergeStored fills a missing server or token from the credentials saved by login. The
+// stored token is only used for the stored server.
+func (c *Config) mergeStored() error {
+	creds, ok, err := LoadCredentials(c.Profile)
+	if err != nil || !ok {
+		return err
+	}
+	path, _ := CredentialsPath()
+	stored := Config{}
+	server := c.Server
+	if server == "" {
+		server, stored.Server = creds.Server, creds.Server
+	}
+	if !c.hasTokenSource() {
+		stored.Token = creds.tokenFor(server)
+	}
+	c.merge(stored, path)
+	return nil
+}
+
+// Profiles lists the profiles in the config file LoadConfig would read.
+func Profiles(file string) ([]string, error) {
+	v, _, err := readConfigFile(file)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	var names []string
+	for name := range v.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func readConfigFile(file string) (*viper.Viper, string, error) {
+	if file == "" {
+		file = os.Getenv("CONDUIT_CONFIG")
+	}
+	if file == "" {
+		var dirs []string
+		if wd, err := os.Getwd(); err == nil {
+			dirs = append(dirs, wd)
+		}
+		if home, err := homedir.Dir(); err == nil {
+			dirs = append(dirs, home)
+		}
+		for _, dir := range dirs {
+			for _, name := range []string{ConfigFileName, legacyConfigFileName} {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					file = filepath.Join(dir, name)
+					break
+				}
+			}
+			if file != "" {
+				break
+			}
+		}
+	}
+	if file == "" {
+		return nil, "", nil
+	}
+	v := viper.New()
+	v.SetConfigFile(file)
+	v.SetConfigType("toml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, "", fmt.Errorf("reading %v: %v", file, err)
+	}
+	return v, file, nil
+}
+
+func describeFile(file string) string {
+	if file == "" {
+		return "any config file (none found)"
+	}
+	return file
+}
+
+// configSection reads the settings at the top of v.
+func configSection(v *viper.Viper) (Config, error) {
+	c := Config{
+		Server:             v.GetString("server"),
+		Servers:            v.GetStringSlice("servers"),
+		Token:              v.GetString("token"),
+		TokenCommand:       v.GetString("token_command"),
+		TokenFile:          v.GetString("token_file"),
+		EncryptedTokenFile: v.GetString("encrypted_token_file"),
+		KeyringService:     v.GetString("keyring_service"),
+		KeyringAccount:     v.GetString("keyring_account"),
+		PageSize:           v.GetInt("page_size"),
+		TLS: TLSConfig{
+			CAFile:        v.GetString("tls.ca_file"),
+			CertFile:      v.GetString("tls.cert_file"),
+			KeyFile:       v.GetString("tls.key_file"),
+			KeyPassphrase: v.GetString("tls.key_passphrase"),
+			ServerName:    v.GetString("tls.server_name"),
+		},
+	}
+	if v.IsSet("tls.insecure_skip_verify") {
+		skip := v.GetBool("tls.insecure_skip_verify")
+		c.TLS.InsecureSkipVerify = &skip
+	}
+	var err error
+	c.Timeout, err = parseTimeout(v.Get("timeout"))
+	return c, err
+}
+
+// parseTimeout reads a timeout written as a duration ("90s") or as a number of seconds.
+func parseTimeout(raw interface{}) (time.Duration, error) {
+	switch t := raw.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return time.Duration(t) * time.Second, nil
+	case int64:
+		return time.Duration(t) * time.Second, nil
+	case float64:
+		return time.Duration(t * float64(time.Second)), nil
+	case string:
+		if secs, err := strconv.Atoi(t); err == nil {
+			return time.Duration(secs) * time.Second, nil
+		}
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timeout %q", t)
+		}
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid timeout %v", raw)
+}
+
+func envConfig() (Config, error) {
+	c := Config{
+		Server:             os.Getenv("CONDUIT_SERVER"),
+		Token:              os.Getenv("CONDUIT_TOKEN"),
+		TokenCommand:       os.Getenv("CONDUIT_TOKEN_COMMAND"),
+		TokenFile:          os.Getenv("CONDUIT_TOKEN_FILE"),
+		EncryptedTokenFile: os.Getenv("CONDUIT_ENCRYPTED_TOKEN_FILE"),
+		KeyringService:     os.Getenv("CONDUIT_KEYRING_SERVICE"),
+		KeyringAccount:     os.Getenv("CONDUIT_KEYRING_ACCOUNT"),
+		TLS:                TLSConfig{KeyPassphrase: os.Getenv("CONDUIT_TLS_KEY_PASSPHRASE")},
+	}
+	if s := os.Getenv("CONDUIT_SERVERS"); s != "" {
+		for _, server := range strings.Split(s, ",") {
+			if server = strings.TrimSpace(server); server != "" {
+				c.Servers = append(c.Servers, server)
+			}
+		}
+	}
+	if s := os.Getenv("CONDUIT_PAGE_SIZE"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return c, fmt.Errorf("invalid CONDUIT_PAGE_SIZE %q", s)
+		}
+		c.PageSize = n
+	}
+	if s := os.Getenv("CONDUIT_TIMEOUT"); s != "" {
+		d, err := parseTimeout(s)
+		if err != nil {
+			return c, fmt.Errorf("CONDUIT_TIMEOUT: %v", err)
+		}
+		c.Timeout = d
+	}
+	return c, nil
+}
+
+func flagConfig(flags *pflag.FlagSet) (Config, error) {
+	c := Config{}
+	str := func(names ...string) string {
+		for _, name := range names {
+			if f := flags.Lookup(name); f != nil && f.Changed {
+				return f.Value.String()
+			}
+		}
+		return ""
+	}
+	c.Server = str("server", "CONDUIT_SERVER")
+	c.Token = str("token", "CONDUIT_TOKEN")
+	c.TokenCommand = str("token-command")
+	c.TokenFile = str("token-file")
+	if s := str("page-size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return c, fmt.Errorf("invalid --page-size %q", s)
+		}
+		c.PageSize = n
+	}
+	if s := str("timeout"); s != "" {
+		d, err := parseTimeout(s)
+		if err != nil {
+			return c, fmt.Errorf("--timeout: %v", err)
+		}
+		c.Timeout = d
+	}
+	return c, nil
+}
+
+// merge lays the settings in o over c, recording source for each one o sets.
+func (c *Config) merge(o Config, source string) {
+	set := func(name string) { c.Sources[name] = source }
+	if o.Server != "" {
+		c.Server = o.Server
+		set("server")
+	}
+	if len(o.Servers) > 0 {
+		c.Servers = o.Servers
+		set("servers")
+	}
+	if o.hasTokenSource() {
+		// Token sources from different layers would fight, so the higher layer's
+		// choice replaces them all.
+		tokens := map[string]string{
+			"token":                o.Token,
+			"token_command":        o.TokenCommand,
+			"token_file":           o.TokenFile,
+			"encrypted_token_file": o.EncryptedTokenFile,
+			"keyring_service":      o.KeyringService,
+			"keyring_account":      o.KeyringAccount,
+		}
+		for name, value := range tokens {
+			delete(c.Sources, name)
+			if value != "" {
+				set(name)
+			}
+		}
+		c.Token, c.TokenCommand, c.TokenFile = o.Token, o.TokenCommand, o.TokenFile
+		c.EncryptedTokenFile, c.KeyringService, c.KeyringAccount = o.EncryptedTokenFile, o.KeyringService, o.KeyringAccount
+	}
+	if o.PageSize != 0 {
+		c.PageSize = o.PageSize
+		set("page_size")
+	}
+	if o.Timeout != 0 {
+		c.Timeout = o.Timeout
+		set("timeout")
+	}
+	if o.TLS.CAFile != "" {
+		c.TLS.CAFile = o.TLS.CAFile
+		set("tls.ca_file")
+	}
+	if o.TLS.CertFile != "" {
+		c.TLS.CertFile = o.TLS.CertFile
+		set("tls.cert_file")
+	}
+	if o.TLS.KeyFile != "" {
+		c.TLS.KeyFile = o.TLS.KeyFile
+		set("tls.key_file")
+	}
+	if o.TLS.KeyPassphrase != "" {
+		c.TLS.KeyPassphrase = o.TLS.KeyPassphrase
+		set("tls.key_passphrase")
+	}
+	if o.TLS.ServerName != "" {
+		c.TLS.ServerName = o.TLS.ServerName
+		set("tls.server_name")
+	}
+	if o.TLS.InsecureSkipVerify != nil {
+		c.TLS.InsecureSkipVerify = o.TLS.InsecureSkipVerify
+		set("tls.insecure_skip_verify")
+	}
+}
+
+// Redacted is a copy of c that is safe to print, with the token, the token command and
+// the TLS key passphrase replaced. A token command can carry a secret in its arguments.
+func (c Config) Redacted() Config {
+	for _, secret := range []*string{&c.Token, &c.TokenCommand, &c.TLS.KeyPassphrase} {
+		if *secret != "" {
+			*secret = "<redacted>"
+		}
+	}
+	return c
+}
+
+func (c Config) hasTokenSource() bool {
+	return c.Token != "" || c.TokenCommand != "" || c.TokenFile != "" || c.EncryptedTokenFile != "" ||
+		c.KeyringService != "" || c.KeyringAccount != ""
+}
+
+// TokenSource is where the token comes from, or nil when none is configured. Setting
+// more than one is an error, since it isn't clear which was meant.
+func (c *Config) TokenSource() (TokenSource, error) {
+	var sources []TokenSource
+	if c.Token != "" {
+		sources = append(sources, StaticToken(c.Token))
+	}
+	if c.TokenCommand != "" {
+		sources = append(sources, CommandToken{Command: c.TokenCommand})
+	}
+	if c.TokenFile != "" {
+		sources = append(sources, FileToken{Path: c.TokenFile})
+	}
+	if c.EncryptedTokenFile != "" {
+		sources = append(sources, EncryptedFileToken{Path: c.EncryptedTokenFile, Passphrase: os.Getenv("CONDUIT_TOKEN_PASSPHRASE")})
+	}
+	if c.KeyringService != "" || c.KeyringAccount != "" {
+		sources = append(sources, KeyringToken{Service: c.KeyringService, Account: c.KeyringAccount})
+	}
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sources[0], nil
+	}
+	return nil, fmt.Errorf("more than one of token, token_command, token_file, encrypted_token_file and keyring_service is set")
+}
+
+// ResolveToken fetches the token from its source.
+func (c *Config) ResolveToken() (string, error) {
+	src, err := c.TokenSource()
+	if err != nil || src == nil {
+		return "", err
+	}
+	return src.Token(context.Background())
+}
+
+// NewClient makes a client from the config, with its TLS settings, page size and timeout.
+// The token is fetched from its source when a request needs it, and reused for
+// TokenCacheTTL, so a token that rotates is picked up. Unlike the package NewClient it
+// returns an error rather than exiting.
+func (c *Config) NewClient() (*ConduitClient, error) {
+	if c.Server == "" {
+		return nil, fmt.Errorf("no Conduit server configured: set server in %v, CONDUIT_SERVER or --server", ConfigFileName)
+	}
+	src, err := c.TokenSource()
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return nil, fmt.Errorf("no Conduit token configured: set token, token_command, token_file, encrypted_token_file or keyring_service in %v, CONDUIT_TOKEN or --token", ConfigFileName)
+	}
+	client := &ConduitClient{ConduitServer: c.Server, TokenSource: CachedToken(src, TokenCacheTTL), Servers: c.Servers}
+	client.Options = QueryOptions{PageSize: c.PageSize, Timeout: c.Timeout}
+	if err := client.Options.Validate(); err != nil {
+		return nil, err
+	}
+	if client.HTTPClient, err = c.HTTPClient(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// HTTPClient is an http.Client with the TLS settings, or nil when there are none.
+func (c *Config) HTTPClient() (*http.Client, error) {
+	if c.TLS == (TLSConfig{}) {
+		return nil, nil
+	}
+	tlsConfig, err := c.TLS.build()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+func (t TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{ServerName: t.ServerName, InsecureSkipVerify: t.SkipVerify()}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %v", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := t.keyPair()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// keyPair loads the client certificate, decrypting its key with KeyPassphrase when it's an
+// encrypted PKCS#8 key. Keys encrypted the legacy OpenSSL way, with a DEK-Info header, are
+// refused: that scheme is weak and can't tell a wrong passphrase from a corrupt key.
+func (t TLSConfig) keyPair() (tls.Certificate, error) {
+	certPEM, err := ioutil.ReadFile(t.CertFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(t.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no PEM key found in %v", t.KeyFile)
+	}
+	if _, legacy := block.Headers["DEK-Info"]; legacy {
+		return tls.Certificate{}, fmt.Errorf("%v uses legacy PEM encryption, which isn't supported: "+
+			"convert it with openssl pkcs8 -topk8 -v2 aes-256-cbc", t.KeyFile)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		if t.KeyPassphrase == "" {
+			return tls.Certificate{}, fmt.Errorf("%v is encrypted, set tls.key_passphrase", t.KeyFile)
+		}
+		der, err := decryptPKCS8(block.Bytes, []byte(t.KeyPassphrase))
+		if err == nil {
+			// Padding can come out right by chance, so a key that doesn't parse means the same.
+			if _, perr := x509.ParsePKCS8PrivateKey(der); perr != nil {
+				err = errKeyPassphrase
+			}
+		}
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("decrypting %v: %v", t.KeyFile, err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
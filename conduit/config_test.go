@@ -0,0 +1,257 @@
+package conduit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+const testConfig = `
+default_profile = "dev"
+page_size = 500
+token = "toptoken"
+
+[tls]
+insecure_skip_verify = true
+
+[profiles.dev]
+server = "dev.example.com"
+timeout = "90s"
+
+[profiles.prod]
+server = "prod.example.com"
+token_command = "echo prodtoken"
+timeout = 300
+
+[profiles.prod.tls]
+server_name = "conduit.prod"
+insecure_skip_verify = false
+`
+
+// setenv sets an environment variable for the rest of the test.
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "conduit-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, ConfigFileName)
+	if err := ioutil.WriteFile(file, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func clearConduitEnv(t *testing.T) {
+	for _, key := range []string{"CONDUIT_PROFILE", "CONDUIT_CONFIG", "CONDUIT_SERVER", "CONDUIT_SERVERS",
+		"CONDUIT_TOKEN", "CONDUIT_TOKEN_COMMAND", "CONDUIT_TOKEN_FILE", "CONDUIT_ENCRYPTED_TOKEN_FILE",
+		"CONDUIT_KEYRING_SERVICE", "CONDUIT_KEYRING_ACCOUNT", "CONDUIT_PAGE_SIZE", "CONDUIT_TIMEOUT",
+		"CONDUIT_TLS_KEY_PASSPHRASE"} {
+		setenv(t, key, "")
+	}
+	setenv(t, "CONDUIT_CREDENTIALS", filepath.Join(os.TempDir(), "conduit-no-such-credentials.json"))
+}
+
+func TestLoadConfig_Profiles(t *testing.T) {
+	clearConduitEnv(t)
+	file := writeConfig(t, testConfig)
+
+	cfg, err := LoadConfig(LoadOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "dev" || cfg.Server != "dev.example.com" || cfg.Token != "toptoken" || cfg.PageSize != 500 || cfg.Timeout != 90*time.Second {
+		t.Errorf("Unexpected default profile config: %+v", cfg)
+	}
+	if !cfg.TLS.SkipVerify() {
+		t.Errorf("Expected the dev profile to keep the top level's insecure_skip_verify")
+	}
+	if cfg.Sources["server"] != "profile dev" || cfg.Sources["page_size"] != file {
+		t.Errorf("Unexpected sources: %v", cfg.Sources)
+	}
+
+	setenv(t, "CONDUIT_PROFILE", "prod")
+	cfg, err = LoadConfig(LoadOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The profile's token command replaces the top-level token.
+	if cfg.Profile != "prod" || cfg.Token != "" || cfg.TokenCommand != "echo prodtoken" || cfg.Timeout != 5*time.Minute || cfg.TLS.ServerName != "conduit.prod" {
+		t.Errorf("Unexpected prod config: %+v", cfg)
+	}
+	if cfg.TLS.SkipVerify() || cfg.Sources["tls.insecure_skip_verify"] != "profile prod" {
+		t.Errorf("Expected the prod profile to turn insecure_skip_verify back off: %v", cfg.Sources)
+	}
+	client, err := cfg.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.TokenSource == nil || client.HTTPClient == nil || client.Options.PageSize != 500 {
+		t.Fatalf("Unexpected client: %+v", client)
+	}
+	if token, err := client.TokenSource.Token(context.Background()); err != nil || token != "prodtoken" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: prodtoken", token, err)
+	}
+
+	if _, err := LoadConfig(LoadOptions{File: file, Profile: "staging"}); err == nil {
+		t.Errorf("Expected an error for a missing profile")
+	}
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	clearConduitEnv(t)
+	file := writeConfig(t, testConfig)
+	setenv(t, "CONDUIT_SERVER", "env.example.com")
+	setenv(t, "CONDUIT_PAGE_SIZE", "200")
+	setenv(t, "CONDUIT_TLS_KEY_PASSPHRASE", "hunter2")
+	setenv(t, "CONDUIT_SERVERS", " a.example.com, b.example.com ,")
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("server", "", "")
+	flags.Int("page-size", 0, "")
+	flags.String("token", "", "")
+	if err := flags.Parse([]string{"--page-size", "50"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(LoadOptions{File: file, Flags: flags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server != "env.example.com" || cfg.PageSize != 50 || cfg.Timeout != 90*time.Second {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if len(cfg.Servers) != 2 || cfg.Servers[0] != "a.example.com" || cfg.Servers[1] != "b.example.com" {
+		t.Errorf("Actual: %q\n=====\nExpected: CONDUIT_SERVERS split and trimmed", cfg.Servers)
+	}
+	if cfg.Sources["server"] != "environment" || cfg.Sources["page_size"] != "flags" {
+		t.Errorf("Unexpected sources: %v", cfg.Sources)
+	}
+	if r := cfg.Redacted(); r.Token != "<redacted>" || cfg.Token != "toptoken" {
+		t.Errorf("Actual: %v\n=====\nExpected: the token redacted in a copy only", r.Token)
+	}
+	if r := cfg.Redacted(); r.TLS.KeyPassphrase != "<redacted>" || cfg.TLS.KeyPassphrase != "hunter2" {
+		t.Errorf("Actual: %v\n=====\nExpected: the key passphrase redacted in a copy only", r.TLS.KeyPassphrase)
+	}
+	if r := (Config{TokenCommand: "vault read -field=token secret/conduit"}).Redacted(); r.TokenCommand != "<redacted>" {
+		t.Errorf("Actual: %v\n=====\nExpected: the token command redacted", r.TokenCommand)
+	}
+}
+
+func TestLoadConfig_Legacy(t *testing.T) {
+	clearConduitEnv(t)
+	file := writeConfig(t, "CONDUIT_SERVER = \"old.example.com\"\nCONDUIT_TOKEN = \"oldtoken\"\n")
+	cfg, err := LoadConfig(LoadOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server != "old.example.com" || cfg.Token != "oldtoken" {
+		t.Errorf("Unexpected legacy config: %+v", cfg)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for raw, expected := range map[interface{}]time.Duration{
+		nil: 0, 30: 30 * time.Second, int64(5): 5 * time.Second, "45": 45 * time.Second, "2m": 2 * time.Minute,
+	} {
+		if d, err := parseTimeout(raw); err != nil || d != expected {
+			t.Errorf("%v. Actual: %v (%v)\n=====\nExpected: %v", raw, d, err, expected)
+		}
+	}
+	if _, err := parseTimeout("soon"); err == nil {
+		t.Errorf("Expected an error for an invalid timeout")
+	}
+}
+
+func TestTLSConfig_EncryptedKey(t *testing.T) {
+	for _, keyFile := range []string{"testdata/client_aes256.key", "testdata/client_aes128_sha1.key"} {
+		tlsConfig := TLSConfig{CertFile: "testdata/client.crt", KeyFile: keyFile, KeyPassphrase: "hunter2"}
+		built, err := tlsConfig.build()
+		if err != nil || len(built.Certificates) != 1 {
+			t.Errorf("%v: Expected the encrypted key to load: %v", keyFile, err)
+		}
+		tlsConfig.KeyPassphrase = "wrong"
+		if _, err := tlsConfig.build(); err == nil || !strings.Contains(err.Error(), "incorrect passphrase") {
+			t.Errorf("%v. Actual: %v\n=====\nExpected: an incorrect passphrase error", keyFile, err)
+		}
+		tlsConfig.KeyPassphrase = ""
+		if _, err := tlsConfig.build(); err == nil || !strings.Contains(err.Error(), "is encrypted") {
+			t.Errorf("%v. Actual: %v\n=====\nExpected: an error asking for the passphrase", keyFile, err)
+		}
+	}
+}
+
+func TestTLSConfig_LegacyEncryptedKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The headers openssl ec -aes256 writes. The key is refused before anything is decrypted.
+	legacy := &pem.Block{Type: "EC PRIVATE KEY", Headers: map[string]string{
+		"Proc-Type": "4,ENCRYPTED",
+		"DEK-Info":  "AES-256-CBC,00112233445566778899AABBCCDDEEFF",
+	}, Bytes: keyDER}
+	certFile := writeConfig(t, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	keyFile := writeConfig(t, string(pem.EncodeToMemory(legacy)))
+
+	tlsConfig := TLSConfig{CertFile: certFile, KeyFile: keyFile, KeyPassphrase: "hunter2"}
+	if _, err := tlsConfig.build(); err == nil || !strings.Contains(err.Error(), "legacy PEM encryption") {
+		t.Errorf("Actual: %v\n=====\nExpected: legacy PEM encryption refused", err)
+	}
+}
+
+func TestConfig_NewClient_RotatedToken(t *testing.T) {
+	old := TokenCacheTTL
+	TokenCacheTTL = 0
+	defer func() { TokenCacheTTL = old }()
+	file := writeConfig(t, "first\n")
+	cfg := &Config{Server: "example.com", TokenFile: file}
+	client, err := cfg.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"first", "second"} {
+		if err := ioutil.WriteFile(file, []byte(expected+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if token, err := client.TokenSource.Token(context.Background()); err != nil || token != expected {
+			t.Errorf("Actual: %q (%v)\n=====\nExpected: %v", token, err, expected)
+		}
+	}
+}
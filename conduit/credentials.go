@@ -0,0 +1,130 @@
+package conduit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// DefaultProfile names the stored credentials used when no profile is chosen.
+const DefaultProfile = "default"
+
+// Credentials are a server and token saved by the driver's login command.
+type Credentials struct {
+	Server  string    `json:"server"`
+	Token   string    `json:"token"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// tokenFor returns the stored token when it was issued for server, and "" when server is
+// another one, so a token is never sent to a server it wasn't made for.
+func (c Credentials) tokenFor(server string) string {
+	if !strings.EqualFold(strings.TrimSpace(server), strings.TrimSpace(c.Server)) {
+		return ""
+	}
+	return c.Token
+}
+
+type credentialsFile struct {
+	Profiles map[string]Credentials `json:"profiles"`
+}
+
+// CredentialsPath is where credentials are stored: $CONDUIT_CREDENTIALS, or
+// .conduit/credentials.json in the home directory.
+func CredentialsPath() (string, error) {
+	if path := os.Getenv("CONDUIT_CREDENTIALS"); path != "" {
+		return path, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".conduit", "credentials.json"), nil
+}
+
+func readCredentials() (credentialsFile, string, error) {
+	f := credentialsFile{Profiles: map[string]Credentials{}}
+	path, err := CredentialsPath()
+	if err != nil {
+		return f, "", err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return f, path, nil
+	}
+	if err := checkPrivate(path); err != nil {
+		return f, path, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return f, path, err
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return f, path, err
+	}
+	if f.Profiles == nil {
+		f.Profiles = map[string]Credentials{}
+	}
+	return f, path, nil
+}
+
+func writeCredentials(f credentialsFile, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(path, b)
+}
+
+// LoadCredentials returns the credentials stored for profile, an empty profile meaning
+// DefaultProfile. ok is false when there are none.
+func LoadCredentials(profile string) (creds Credentials, ok bool, err error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	f, _, err := readCredentials()
+	if err != nil {
+		return Credentials{}, false, err
+	}
+	creds, ok = f.Profiles[profile]
+	return creds, ok, nil
+}
+
+// SaveCredentials stores creds for profile in a file only its owner can read.
+func SaveCredentials(profile string, creds Credentials) error {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	f, path, err := readCredentials()
+	if err != nil {
+		return err
+	}
+	if creds.SavedAt.IsZero() {
+		creds.SavedAt = time.Now()
+	}
+	f.Profiles[profile] = creds
+	return writeCredentials(f, path)
+}
+
+// DeleteCredentials removes the credentials stored for profile, reporting whether there were any.
+func DeleteCredentials(profile string) (bool, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	f, path, err := readCredentials()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := f.Profiles[profile]; !ok {
+		return false, nil
+	}
+	delete(f.Profiles, profile)
+	return true, writeCredentials(f, path)
+}
@@ -0,0 +1,137 @@
+package conduit
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestCredentials(t *testing.T) {
+	path := filepath.Join(tempDir(t), "nested", "credentials.json")
+	setenv(t, "CONDUIT_CREDENTIALS", path)
+	setenv(t, "CONDUIT_PROFILE", "")
+
+	if _, ok, err := LoadCredentials("prod"); ok || err != nil {
+		t.Fatalf("Expected no credentials yet: %v", err)
+	}
+	if err := SaveCredentials("", Credentials{Server: "dev.example.com", Token: "devtoken"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCredentials("prod", Credentials{Server: "prod.example.com", Token: "prodtoken"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Actual: %v (%v)\n=====\nExpected: -rw-------", info.Mode().Perm(), err)
+	}
+	creds, ok, err := LoadCredentials("prod")
+	if !ok || err != nil || creds.Token != "prodtoken" || creds.SavedAt.IsZero() {
+		t.Errorf("Unexpected credentials: %+v %v (%v)", creds, ok, err)
+	}
+
+	c := NewClient("", "")
+	if c.ConduitServer != "dev.example.com" || c.ConduitToken != "devtoken" {
+		t.Errorf("NewClient didn't use the default profile's credentials: %v", c.ConduitServer)
+	}
+	setenv(t, "CONDUIT_PROFILE", "prod")
+	if c := NewClient("prod.example.com", ""); c.ConduitToken != "prodtoken" {
+		t.Errorf("NewClient should fill the token for the stored server: %+v", c)
+	}
+	if c := NewClient("other.example.com", ""); c.ConduitServer != "other.example.com" || c.ConduitToken != "" {
+		t.Errorf("NewClient sent the stored token to another server: %+v", c)
+	}
+
+	if removed, err := DeleteCredentials("prod"); !removed || err != nil {
+		t.Errorf("Expected prod to be removed: %v", err)
+	}
+	if removed, _ := DeleteCredentials("prod"); removed {
+		t.Errorf("prod removed twice")
+	}
+	if _, ok, _ := LoadCredentials(""); !ok {
+		t.Errorf("Removing prod removed the default profile too")
+	}
+
+	os.Chmod(path, 0644)
+	if _, _, err := LoadCredentials(""); err == nil {
+		t.Errorf("Expected a readable credentials file to be refused")
+	}
+}
+
+func TestLoadConfig_StoredProfile(t *testing.T) {
+	clearConduitEnv(t)
+	setenv(t, "CONDUIT_CREDENTIALS", filepath.Join(tempDir(t), "credentials.json"))
+	file := writeConfig(t, testConfig)
+
+	if _, err := LoadConfig(LoadOptions{File: file, Profile: "staging"}); err == nil {
+		t.Errorf("Expected an error for a profile that isn't anywhere")
+	}
+	cfg, err := LoadConfig(LoadOptions{File: file, Profile: "staging", NewProfile: true})
+	if err != nil || cfg.Profile != "staging" {
+		t.Errorf("Login should be able to start a new profile: %+v (%v)", cfg, err)
+	}
+
+	if err := SaveCredentials("staging", Credentials{Server: "staging.example.com", Token: "stagingtoken"}); err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, "CONDUIT_PROFILE", "staging")
+	cfg, err = LoadConfig(LoadOptions{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The top-level token is there, so only the server comes from the stored credentials.
+	if cfg.Profile != "staging" || cfg.Server != "staging.example.com" || cfg.Token != "toptoken" {
+		t.Errorf("Unexpected config for a profile made by login: %+v", cfg)
+	}
+
+	noToken := writeConfig(t, "[profiles.staging]\n")
+	cfg, err = LoadConfig(LoadOptions{File: noToken})
+	if err != nil || cfg.Server != "staging.example.com" || cfg.Token != "stagingtoken" {
+		t.Errorf("Actual: %+v (%v)\n=====\nExpected: the stored server and token", cfg, err)
+	}
+	setenv(t, "CONDUIT_SERVER", "other.example.com")
+	cfg, err = LoadConfig(LoadOptions{File: noToken})
+	if err != nil || cfg.Server != "other.example.com" || cfg.Token != "" {
+		t.Errorf("Actual: %+v (%v)\n=====\nExpected: no stored token for another server", cfg, err)
+	}
+}
+
+func TestConduitClient_DeviceLogin(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	defer func(d time.Duration) { devicePollUnit = d }(devicePollUnit)
+	devicePollUnit = time.Millisecond
+
+	httpmock.RegisterResponder("POST", "https://blah/api/auth/device/code",
+		httpmock.NewStringResponder(200, `{"device_code":"dev1","user_code":"ABCD-EFGH","verification_uri":"https://blah/device","interval":1,"expires_in":60}`))
+	polls := 0
+	httpmock.RegisterResponder("POST", "https://blah/api/auth/device/token",
+		func(req *http.Request) (*http.Response, error) {
+			if polls++; polls < 3 {
+				return httpmock.NewStringResponse(400, `{"error":"authorization_pending"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"access_token":"newtoken"}`), nil
+		})
+
+	c := &ConduitClient{ConduitServer: "blah"}
+	var shown DeviceCode
+	token, err := c.DeviceLogin(context.Background(), func(code DeviceCode) { shown = code })
+	if err != nil || token != "newtoken" || shown.UserCode != "ABCD-EFGH" || polls != 3 {
+		t.Errorf("Actual: %q %+v after %v polls (%v)\n=====\nExpected: newtoken", token, shown, polls, err)
+	}
+
+	httpmock.RegisterResponder("POST", "https://blah/api/auth/device/token", httpmock.NewStringResponder(502, `<html>Bad Gateway</html>`))
+	if _, err := c.DeviceLogin(context.Background(), func(DeviceCode) {}); err == nil || !strings.Contains(err.Error(), "reading device token") {
+		t.Errorf("Actual: %v\n=====\nExpected: an error reading the device token", err)
+	}
+
+	httpmock.RegisterResponder("POST", "https://blah/api/auth/device/code", httpmock.NewStringResponder(404, ``))
+	if _, err := c.DeviceLogin(context.Background(), func(DeviceCode) {}); err != ErrDeviceLoginUnsupported {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", err, ErrDeviceLoginUnsupported)
+	}
+}
@@ -0,0 +1,201 @@
+package conduit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetBytes is about how much of a payload a DecodeError quotes.
+const snippetBytes = 80
+
+// DecodeError means a payload from Conduit couldn't be decoded into what was expected,
+// such as an HTML error page from a proxy in place of JSON.
+type DecodeError struct {
+	Endpoint    string // empty when decoding a payload that didn't come from a request here
+	StatusCode  int
+	ContentType string
+	Offset      int64  // where in the payload decoding failed, or -1 when it isn't known
+	Snippet     string // the payload around Offset, or its start
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	var b strings.Builder
+	b.WriteString("decoding Conduit response")
+	if e.Endpoint != "" {
+		fmt.Fprintf(&b, " from %v", e.Endpoint)
+	}
+	if e.ContentType != "" && !strings.Contains(e.ContentType, "json") {
+		fmt.Fprintf(&b, " (Content-Type %v)", e.ContentType)
+	}
+	fmt.Fprintf(&b, ": %v, near %q", e.Err, e.Snippet)
+	return b.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeJSON unmarshals payload into target. With strict set, fields target has no
+// place for are an error too.
+func decodeJSON(payload []byte, target interface{}, strict bool) error {
+	if err := json.Unmarshal(payload, target); err != nil {
+		offset := int64(-1)
+		switch t := err.(type) {
+		case *json.SyntaxError:
+			offset = t.Offset
+		case *json.UnmarshalTypeError:
+			offset = t.Offset
+		}
+		return &DecodeError{Offset: offset, Snippet: snippet(payload, offset), Err: err}
+	}
+	if !strict {
+		return nil
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(payload))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return &DecodeError{Offset: -1, Snippet: snippet(payload, -1), Err: err}
+	}
+	if field, name := unknownField(v, reflect.TypeOf(target), ""); field != "" {
+		offset := int64(bytes.Index(payload, []byte(fmt.Sprintf("%q", name))))
+		return &DecodeError{Offset: offset, Snippet: snippet(payload, offset), Err: fmt.Errorf("unknown field %v", field)}
+	}
+	return nil
+}
+
+// decodeResponse reads resp's body into target, describing any failure with a DecodeError.
+func decodeResponse(resp *http.Response, endpoint string, target interface{}, strict bool) error {
+	payload, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return describeResponse(decodeJSON(payload, target, strict), resp, endpoint)
+}
+
+// describeResponse fills in where a DecodeError's payload came from.
+func describeResponse(err error, resp *http.Response, endpoint string) error {
+	if e, ok := err.(*DecodeError); ok {
+		e.StatusCode = resp.StatusCode
+		e.ContentType = resp.Header.Get("Content-Type")
+		e.Endpoint = endpoint
+		if e.Endpoint == "" && resp.Request != nil {
+			e.Endpoint = resp.Request.URL.RequestURI()
+		}
+	}
+	return err
+}
+
+// snippet quotes payload around offset, or from its start when offset is negative.
+func snippet(payload []byte, offset int64) string {
+	start := int64(0)
+	if offset > snippetBytes/2 {
+		start = offset - snippetBytes/2
+	}
+	if start > int64(len(payload)) {
+		start = int64(len(payload))
+	}
+	end := start + snippetBytes
+	if end > int64(len(payload)) {
+		end = int64(len(payload))
+	}
+	s := payload[start:end]
+	// Don't start or end partway through a character.
+	for len(s) > 0 && !utf8.RuneStart(s[0]) {
+		s = s[1:]
+	}
+	for len(s) > 0 && !utf8.Valid(s) {
+		s = s[:len(s)-1]
+	}
+	return string(s)
+}
+
+// unknownField finds a key in v, decoded from JSON, that t has no field for. It returns
+// the key's path and the key itself, or empty strings when there is none.
+func unknownField(v interface{}, t reflect.Type, path string) (string, string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return "", ""
+		}
+		fields := map[string]reflect.Type{}
+		jsonFields(t, fields)
+		keys := make([]string, 0, len(object))
+		for k := range object {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fieldPath := k
+			if path != "" {
+				fieldPath = path + "." + k
+			}
+			fieldType, ok := fields[strings.ToLower(k)]
+			if !ok {
+				return fieldPath, k
+			}
+			if p, name := unknownField(object[k], fieldType, fieldPath); p != "" {
+				return p, name
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		array, ok := v.([]interface{})
+		if !ok {
+			return "", ""
+		}
+		for i, item := range array {
+			if p, name := unknownField(item, t.Elem(), fmt.Sprintf("%v[%d]", path, i)); p != "" {
+				return p, name
+			}
+		}
+	}
+	return "", ""
+}
+
+// jsonFields collects the lower-cased JSON names of t's fields, as encoding/json matches them.
+func jsonFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				// Fields of the outer struct win over those it embeds.
+				inner := map[string]reflect.Type{}
+				jsonFields(embedded, inner)
+				for k, v := range inner {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+}
@@ -0,0 +1,99 @@
+package conduit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestGetOnTheWire_DecodeError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("https://%v/api/metadata/databases", viper.GetString("CONDUIT_SERVER"))
+	page := `<html><head><title>502 Bad Gateway</title></head><body>upstream timed out</body></html>`
+	httpmock.RegisterResponder("GET", url, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, page)
+		resp.Header.Set("Content-Type", "text/html")
+		return resp, nil
+	})
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	var dbs DatabasesStruct
+	err := c.GetOnTheWire("/metadata/databases", &dbs)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Actual: %v\n=====\nExpected: a *DecodeError", err)
+	}
+	if decodeErr.Endpoint != "/metadata/databases" || decodeErr.ContentType != "text/html" || !strings.HasPrefix(decodeErr.Snippet, "<html>") {
+		t.Errorf("Unexpected error: %+v", decodeErr)
+	}
+	if !strings.Contains(err.Error(), "502 Bad Gateway") {
+		t.Errorf("Expected the snippet in the message: %v", err)
+	}
+}
+
+func TestDecodeJSON_Strict(t *testing.T) {
+	payload := `{"columns":[{"name":"code","colType":"int","sqlType":4,"comment":"new in v3"}]}`
+	var schema TableSchemaStruct
+	if err := decodeJSON([]byte(payload), &schema, false); err != nil || len(schema.Columns) != 1 {
+		t.Errorf("Unknown fields should be ignored by default: %v", err)
+	}
+	err := decodeJSON([]byte(payload), &schema, true)
+	decodeErr, ok := err.(*DecodeError)
+	if !ok || !strings.Contains(err.Error(), "unknown field columns[0].comment") || !strings.Contains(decodeErr.Snippet, `"comment"`) {
+		t.Errorf("Actual: %v\n=====\nExpected: unknown field columns[0].comment", err)
+	}
+	// Matching is case-insensitive, as encoding/json's is, and parsed fields aren't on the wire.
+	result := `{"QueryId":"q1","status":"Finished","data":{"columns":["a"],"rows":[{"a":1}],"hasNext":false}}`
+	if err := decodeJSON([]byte(result), &QueryResultStruct{}, true); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := decodeJSON([]byte(`{"queryId":"q1","ParsedRows":[]}`), &QueryResultStruct{}, true); err == nil {
+		t.Errorf("Expected ParsedRows to be an unknown field")
+	}
+}
+
+func TestUnmarshalJsonToQueryResult_Errors(t *testing.T) {
+	// No rows alongside the columns is fine.
+	qrs, err := UnmarshalJsonToQueryResult(`{"queryId":"q1","status":"Finished","data":{"columns":["a"]}}`)
+	if err != nil || len(qrs.ParsedColumns) != 1 || qrs.ParsedRows != nil {
+		t.Errorf("Unexpected result: %+v (%v)", qrs, err)
+	}
+	cases := map[string]string{
+		`{"queryId":"q1","status":`:                           "unexpected end of JSON input",
+		`{"queryId":42}`:                                      "cannot unmarshal number",
+		`{"queryId":"q1","data":{"columns":"a","rows":[]}}`:   "data.columns",
+		`{"queryId":"q1","data":{"columns":[],"rows":[1,2]}}`: "data.rows",
+	}
+	for payload, expected := range cases {
+		_, err := UnmarshalJsonToQueryResult(payload)
+		if _, ok := err.(*DecodeError); !ok || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%v\nActual: %v\n=====\nExpected: a *DecodeError mentioning %v", payload, err, expected)
+		}
+	}
+}
+
+func TestExecuteQuery_DecodeError(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("https://%v/api/query/execute", viper.GetString("CONDUIT_SERVER"))
+	httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(200, `Service Unavailable`))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	err := c.ExecuteQuery(context.Background(), "SELECT 1")
+	if _, ok := err.(*DecodeError); !ok || !strings.Contains(err.Error(), "Service Unavailable") {
+		t.Errorf("Actual: %v\n=====\nExpected: a *DecodeError quoting the response", err)
+	}
+
+	httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(503, `<h1>Service Unavailable</h1>`))
+	err = c.ExecuteQuery(context.Background(), "SELECT 1")
+	if err == nil || !strings.Contains(err.Error(), "Status Code 503 returned with message <h1>Service Unavailable</h1>") {
+		t.Errorf("Actual: %v\n=====\nExpected: the status and the page", err)
+	}
+}
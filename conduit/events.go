@@ -0,0 +1,82 @@
+package conduit
+
+import "time"
+
+// EventType is a step in a query's lifecycle.
+type EventType int
+
+const (
+	// EventSubmitted fires once Conduit has given a query its ID.
+	EventSubmitted EventType = iota
+	// EventStatusChanged fires when a query moves between Running, ResultsReady and Finished.
+	EventStatusChanged
+	// EventPageReceived fires for each page of results, with its row count in Rows.
+	EventPageReceived
+	// EventRetrying fires before another attempt at something that failed, with the attempt number and the error.
+	EventRetrying
+	// EventTimedOut fires when a query runs past its timeout, just before it is cancelled.
+	EventTimedOut
+	// EventCancelled fires when Conduit confirms a cancellation.
+	EventCancelled
+	// EventFailed fires when a request for the query fails, with the error.
+	EventFailed
+)
+
+var eventTypeNames = []string{"submitted", "status changed", "page received", "retrying", "timed out", "cancelled", "failed"}
+
+func (t EventType) String() string {
+	if int(t) < len(eventTypeNames) {
+		return eventTypeNames[t]
+	}
+	return "unknown"
+}
+
+// QueryEvent describes one lifecycle step of a query.
+type QueryEvent struct {
+	Type    EventType
+	QueryId string
+	Label   string // QueryOptions.Label of the query
+	Status  string
+	Rows    int
+	Attempt int
+	Err     error
+	Time    time.Time
+}
+
+// EventHook receives query events. Hooks run on the goroutine driving the query, so they should return quickly.
+type EventHook func(QueryEvent)
+
+// OnEvent adds a hook that is called for every event of every query on this client.
+func (c *ConduitClient) OnEvent(hook EventHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook)
+}
+
+// Events returns a channel that receives every event, holding up to buffer of them.
+// Events are dropped rather than holding up a query when the channel is full.
+func (c *ConduitClient) Events(buffer int) <-chan QueryEvent {
+	ch := make(chan QueryEvent, buffer)
+	c.OnEvent(func(e QueryEvent) {
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+	return ch
+}
+
+func (c *ConduitClient) emit(e QueryEvent) {
+	c.mu.Lock()
+	hooks := c.hooks
+	c.mu.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, hook := range hooks {
+		hook(e)
+	}
+}
@@ -0,0 +1,65 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_QueryEvents(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	pages := []string{
+		`{"queryId":"q1","status":"Finished","data":{"columns":["a"],"rows":[{"a":1},{"a":2}],"hasNext":true}}`,
+		`{"queryId":"q1","status":"Finished","data":{"columns":["a"],"rows":[{"a":3}],"hasNext":false}}`,
+	}
+	calls := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		func(req *http.Request) (*http.Response, error) {
+			calls++
+			return httpmock.NewStringResponse(200, pages[calls-1]), nil
+		})
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	var seen []string
+	c.OnEvent(func(e QueryEvent) {
+		seen = append(seen, fmt.Sprintf("%v:%v:%v", e.Type, e.QueryId, e.Rows))
+	})
+	events := c.Events(10)
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "[submitted:q1:0 status changed:q1:0 page received:q1:2 page received:q1:1]"
+	if fmt.Sprint(seen) != expected {
+		t.Errorf("Actual: \n%v\n=====\nExpected:\n%v", seen, expected)
+	}
+	if len(events) != 4 {
+		t.Errorf("Actual: %v events on the channel, Expected: 4", len(events))
+	}
+}
+
+func TestConduitClient_FailedEvent(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		httpmock.NewStringResponder(500, `{"message":"boom"}`))
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	var failures []QueryEvent
+	c.OnEvent(func(e QueryEvent) {
+		if e.Type == EventFailed {
+			failures = append(failures, e)
+		}
+	})
+	if err := c.ExecuteQuery(context.Background(), "SELECT 1"); err == nil {
+		t.Fatal("Expected ExecuteQuery to fail")
+	}
+	if len(failures) != 1 || failures[0].Err == nil {
+		t.Errorf("Expected one failed event with an error, got %+v", failures)
+	}
+}
@@ -0,0 +1,118 @@
+package conduit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HealthReport is the result of pinging one Conduit server.
+type HealthReport struct {
+	Server        string
+	Reachable     bool          // the server answered
+	Authenticated bool          // the token was accepted: the authenticated call returned 200
+	Latency       time.Duration // one round trip of the authenticated call, without rate limiting or retries
+	Version       string        // optional, empty when the server doesn't report one
+	Capabilities  []string      // optional, empty when the server doesn't report them
+	Err           error         // why the server isn't healthy
+}
+
+// Healthy reports whether the server can be used with this client's token.
+func (r HealthReport) Healthy() bool {
+	return r.Reachable && r.Authenticated && r.Err == nil
+}
+
+// Ping checks ConduitServer. It never exits the process: failures are in the report,
+// and in the error.
+func (c *ConduitClient) Ping(ctx context.Context) (HealthReport, error) {
+	r := c.ping(ctx, c.ConduitServer)
+	return r, r.Err
+}
+
+// HealthCheck pings every configured server, updating which ones are used for new queries.
+func (c *ConduitClient) HealthCheck(ctx context.Context) []HealthReport {
+	var reports []HealthReport
+	for _, s := range c.servers() {
+		reports = append(reports, c.ping(ctx, s))
+	}
+	return reports
+}
+
+// ping lists databases as the cheap authenticated call. Only a 200 counts as the token
+// being accepted. A healthy server is then asked for its version. The call is sent once,
+// without waiting under RequestsPerSecond or retrying a 429, so Latency is one round trip.
+func (c *ConduitClient) ping(ctx context.Context, server string) HealthReport {
+	r := HealthReport{Server: server}
+	if server == "" {
+		r.Err = errors.New("no Conduit server to ping")
+		return r
+	}
+	req, err := c.newRequest(ctx, server, "GET", "/metadata/databases", nil)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	start := time.Now()
+	resp, err := c.roundTrip(server, req)
+	r.Latency = time.Since(start)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	resp.Body.Close()
+	r.Reachable = true
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		r.Err = fmt.Errorf("token rejected by %v: Status Code %v returned", server, resp.StatusCode)
+		return r
+	case resp.StatusCode != http.StatusOK:
+		r.Err = fmt.Errorf("Status Code %v returned by %v", resp.StatusCode, server)
+		if resp.StatusCode >= 500 {
+			c.markServer(server, r.Err)
+		}
+		return r
+	}
+	r.Authenticated = true
+	r.Version, r.Capabilities = c.serverVersion(ctx, server)
+	return r
+}
+
+// serverVersion asks server for its version and capabilities. The endpoint is optional:
+// older servers don't have it, so any failure just leaves both empty, and never makes
+// the server unhealthy.
+func (c *ConduitClient) serverVersion(ctx context.Context, server string) (string, []string) {
+	resp, err := c.do(ctx, server, "GET", "/version", nil)
+	if err != nil {
+		return "", nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil
+	}
+	version := struct {
+		Version      string   `json:"version"`
+		Capabilities []string `json:"capabilities"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", nil
+	}
+	return version.Version, version.Capabilities
+}
+
+// MarshalJSON writes Err as a string.
+func (r HealthReport) MarshalJSON() ([]byte, error) {
+	type report HealthReport
+	out := struct {
+		report
+		Latency string `json:"Latency"`
+		Healthy bool   `json:"Healthy"`
+		Err     string `json:"Err,omitempty"`
+	}{report: report(r), Latency: r.Latency.String(), Healthy: r.Healthy()}
+	if r.Err != nil {
+		out.Err = r.Err.Error()
+	}
+	return json.Marshal(out)
+}
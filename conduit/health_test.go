@@ -0,0 +1,86 @@
+package conduit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_Ping(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://ok.example/api/metadata/databases",
+		httpmock.NewStringResponder(200, `{"databases":["db"]}`))
+	httpmock.RegisterResponder("GET", "https://ok.example/api/version",
+		httpmock.NewStringResponder(200, `{"version":"2.4.1","capabilities":["async","cancel"]}`))
+	httpmock.RegisterResponder("GET", "https://old.example/api/metadata/databases",
+		httpmock.NewStringResponder(200, `{"databases":["db"]}`))
+	httpmock.RegisterResponder("GET", "https://broken.example/api/metadata/databases",
+		httpmock.NewStringResponder(200, `{"databases":["db"]}`))
+	httpmock.RegisterResponder("GET", "https://broken.example/api/version",
+		httpmock.NewStringResponder(500, `not json`))
+	httpmock.RegisterResponder("GET", "https://notfound.example/api/metadata/databases",
+		httpmock.NewStringResponder(404, `{"message":"not found"}`))
+	httpmock.RegisterResponder("GET", "https://badtoken.example/api/metadata/databases",
+		httpmock.NewStringResponder(401, `{"message":"unauthorized"}`))
+	httpmock.RegisterResponder("GET", "https://down.example/api/metadata/databases",
+		httpmock.NewErrorResponder(errors.New("connection refused")))
+
+	c := NewClient("ok.example", viper.GetString("CONDUIT_TOKEN"))
+	r, err := c.Ping(context.Background())
+	if err != nil || !r.Healthy() || r.Version != "2.4.1" || len(r.Capabilities) != 2 {
+		t.Errorf("Unexpected report: %+v (%v)", r, err)
+	}
+
+	c.Servers = []string{"old.example", "badtoken.example", "down.example", "broken.example", "notfound.example"}
+	reports := c.HealthCheck(context.Background())
+	if len(reports) != 6 {
+		t.Fatalf("Actual: %v reports\n=====\nExpected: 6", len(reports))
+	}
+	if old := reports[1]; !old.Healthy() || old.Version != "" {
+		t.Errorf("A server without a version endpoint should still be healthy: %+v", old)
+	}
+	if bad := reports[2]; bad.Healthy() || !bad.Reachable || bad.Authenticated {
+		t.Errorf("Expected a reachable server rejecting the token: %+v", bad)
+	}
+	if down := reports[3]; down.Healthy() || down.Reachable || down.Err == nil {
+		t.Errorf("Expected an unreachable server: %+v", down)
+	}
+	if broken := reports[4]; !broken.Healthy() || broken.Version != "" {
+		t.Errorf("A failing version endpoint shouldn't make the server unhealthy: %+v", broken)
+	}
+	if notFound := reports[5]; notFound.Healthy() || !notFound.Reachable || notFound.Authenticated || notFound.Err == nil {
+		t.Errorf("Only a 200 should count as authenticated: %+v", notFound)
+	}
+	out, err := json.Marshal(reports[2])
+	if err != nil || !strings.Contains(string(out), `"Err":"token rejected`) || !strings.Contains(string(out), `"Healthy":false`) {
+		t.Errorf("Unexpected JSON: %s (%v)", out, err)
+	}
+}
+
+func TestConduitClient_PingOnce(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://busy.example/api/metadata/databases",
+		httpmock.NewStringResponder(429, `{"message":"slow down"}`))
+
+	c := NewClient("busy.example", viper.GetString("CONDUIT_TOKEN"))
+	r, err := c.Ping(context.Background())
+	if err == nil || r.Healthy() || !r.Reachable {
+		t.Errorf("Expected a reachable server refusing the ping: %+v (%v)", r, err)
+	}
+	if calls := httpmock.GetTotalCallCount(); calls != 1 {
+		t.Errorf("Actual: %v requests\n=====\nExpected: 1, without retrying the 429", calls)
+	}
+
+	c.ConduitServer = ""
+	r, err = c.Ping(context.Background())
+	if err == nil || r.Reachable || httpmock.GetTotalCallCount() != 1 {
+		t.Errorf("Expected an error without a request for an empty server: %+v (%v)", r, err)
+	}
+}
@@ -0,0 +1,194 @@
+package conduit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxThrottleRetries bounds how many times one request is retried after Conduit answers 429.
+var MaxThrottleRetries = 5
+
+// DefaultMaxResponseSize is the most a response body may hold when MaxResponseSize is 0.
+const DefaultMaxResponseSize = 256 << 20
+
+// ErrResponseTooLarge is returned reading a response body longer than MaxResponseSize.
+var ErrResponseTooLarge = errors.New("Conduit response is larger than MaxResponseSize")
+
+// defaultThrottleWait is the wait after a 429 that has no usable Retry-After header.
+var defaultThrottleWait = time.Second
+
+// tokenBucket allows rate requests a second on average, and up to burst at once. After a
+// 429 it halves its rate, then creeps back to the configured rate as requests succeed.
+type tokenBucket struct {
+	mu          sync.Mutex
+	limit       float64 // configured requests per second, 0 for no limit
+	rate        float64 // current requests per second
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time // set from Retry-After, holds back every request
+}
+
+func newTokenBucket(limit float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{limit: limit, rate: limit, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait blocks until a request may be sent, or ctx ends.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		delay := b.reserve()
+		if delay <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before trying again.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	if b.rate <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// throttled holds back every request for wait and slows the rate.
+func (b *tokenBucket) throttled(wait time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	if b.limit > 0 {
+		b.rate /= 2
+		if min := b.limit / 16; b.rate < min {
+			b.rate = min
+		}
+		b.tokens = 0
+	}
+}
+
+// succeeded moves the rate back towards the configured one.
+func (b *tokenBucket) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate < b.limit {
+		b.rate += b.limit / 20
+		if b.rate > b.limit {
+			b.rate = b.limit
+		}
+	}
+}
+
+// bucket returns the client's rate limiter, made on first use from RequestsPerSecond and Burst.
+// Even without a rate set it is used to pause requests after a 429.
+func (c *ConduitClient) bucket() *tokenBucket {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limiter == nil {
+		c.limiter = newTokenBucket(c.RequestsPerSecond, c.Burst)
+	}
+	return c.limiter
+}
+
+// acquireQuery waits for one of MaxActiveQueries slots, or ctx to end. The returned func
+// gives the slot back.
+func (c *ConduitClient) acquireQuery(ctx context.Context) (func(), error) {
+	c.mu.Lock()
+	if c.querySlots == nil && c.MaxActiveQueries > 0 {
+		c.querySlots = make(chan struct{}, c.MaxActiveQueries)
+	}
+	slots := c.querySlots
+	c.mu.Unlock()
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-slots }) }, nil
+}
+
+// retryAfter reads a Retry-After header given as seconds or as an HTTP date.
+func retryAfter(resp *http.Response) time.Duration {
+	h := resp.Header.Get("Retry-After")
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultThrottleWait
+}
+
+// limitBody makes reading past MaxResponseSize bytes of body an error.
+func (c *ConduitClient) limitBody(body io.ReadCloser) io.ReadCloser {
+	limit := c.MaxResponseSize
+	if limit == 0 {
+		limit = DefaultMaxResponseSize
+	}
+	if limit < 0 {
+		return body
+	}
+	return &limitedBody{ReadCloser: body, limit: limit}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+// Read reads up to one byte past the limit, to tell a body that ends there from one that goes on.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.limit {
+		return 0, b.tooLarge()
+	}
+	if max := b.limit + 1 - b.read; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n - 1, b.tooLarge()
+	}
+	return n, err
+}
+
+func (b *limitedBody) tooLarge() error {
+	return fmt.Errorf("%w (%v bytes)", ErrResponseTooLarge, b.limit)
+}
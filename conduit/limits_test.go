@@ -0,0 +1,101 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_RequestsPerSecond(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/metadata/databases", server),
+		httpmock.NewStringResponder(200, `{"databases":["db"]}`))
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	c.RequestsPerSecond = 50
+	c.Burst = 2
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		c.GetDatabases()
+	}
+	// Two go straight away, the other five wait 20ms each.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Actual: 7 requests in %v\n=====\nExpected: at least 100ms", elapsed)
+	}
+}
+
+func TestConduitClient_Throttled(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	calls := 0
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/metadata/databases", server),
+		func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls < 3 {
+				resp := httpmock.NewStringResponse(429, `{"message":"slow down"}`)
+				resp.Header.Set("Retry-After", "0")
+				return resp, nil
+			}
+			return httpmock.NewStringResponse(200, `{"databases":["db"]}`), nil
+		})
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	dbs := new(DatabasesStruct)
+	if err := c.getOnTheWire(context.Background(), "/metadata/databases", dbs); err != nil {
+		t.Fatalf("Expected the throttled request to be retried: %v", err)
+	}
+	if calls != 3 || len(dbs.Databases) != 1 {
+		t.Errorf("Actual: %v calls, %v\n=====\nExpected: 3 calls, [db]", calls, dbs.Databases)
+	}
+
+	defer func(n int) { MaxThrottleRetries = n }(MaxThrottleRetries)
+	MaxThrottleRetries = 0
+	calls = 0
+	if err := c.getOnTheWire(context.Background(), "/metadata/databases", dbs); err == nil {
+		t.Errorf("Expected an error once retries ran out")
+	}
+}
+
+func TestConduitClient_MaxActiveQueries(t *testing.T) {
+	c := NewClient("blah", "blahblah")
+	c.MaxActiveQueries = 1
+	release, err := c.acquireQuery(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.acquireQuery(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Actual: %v\n=====\nExpected: the second query to wait until its context ended", err)
+	}
+	release()
+	release()
+	if next, err := c.acquireQuery(context.Background()); err != nil {
+		t.Errorf("Expected a free slot after release: %v", err)
+	} else {
+		next()
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	if d := retryAfter(resp); d != defaultThrottleWait {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", d, defaultThrottleWait)
+	}
+	resp.Header.Set("Retry-After", "3")
+	if d := retryAfter(resp); d != 3*time.Second {
+		t.Errorf("Actual: %v\n=====\nExpected: 3s", d)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d := retryAfter(resp); d < 58*time.Second || d > time.Minute {
+		t.Errorf("Actual: %v\n=====\nExpected: about a minute", d)
+	}
+}
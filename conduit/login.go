@@ -0,0 +1,102 @@
+package conduit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrDeviceLoginUnsupported means the server has no device login endpoint, so the token
+// has to be entered by hand.
+var ErrDeviceLoginUnsupported = errors.New("Conduit server does not support device login")
+
+// devicePollUnit is what DeviceCode.Interval counts in.
+var devicePollUnit = time.Second
+
+// DeviceCode is what the user needs to approve a device login in their browser.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"` // seconds
+	Interval        int    `json:"interval"`   // seconds between polls
+}
+
+// DeviceLogin runs a device authorization flow against ConduitServer: it asks for a code,
+// hands it to prompt to show the user, then polls until the login is approved and returns
+// the token. Servers without the flow give ErrDeviceLoginUnsupported.
+func (c *ConduitClient) DeviceLogin(ctx context.Context, prompt func(DeviceCode)) (string, error) {
+	resp, err := c.do(ctx, c.ConduitServer, "POST", "/auth/device/code", []byte(`{}`))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return "", ErrDeviceLoginUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Status Code %v returned starting device login", resp.StatusCode)
+	}
+	var code DeviceCode
+	if err := json.NewDecoder(resp.Body).Decode(&code); err != nil {
+		return "", fmt.Errorf("reading device code: %v", err)
+	}
+	prompt(code)
+
+	interval := time.Duration(code.Interval) * devicePollUnit
+	if interval <= 0 {
+		interval = 5 * devicePollUnit
+	}
+	expires := time.Duration(code.ExpiresIn) * time.Second
+	if expires <= 0 {
+		expires = 10 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, expires)
+	defer cancel()
+	body, _ := json.Marshal(map[string]string{"device_code": code.DeviceCode})
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("device login was not approved in time: %v", ctx.Err())
+		case <-time.After(interval):
+		}
+		token, pending, err := c.pollDeviceToken(ctx, body)
+		if err != nil {
+			return "", err
+		}
+		if token != "" {
+			return token, nil
+		}
+		if pending == "slow_down" {
+			interval += 5 * devicePollUnit
+		}
+	}
+}
+
+// pollDeviceToken asks once for the token, returning the pending reason while there is none.
+func (c *ConduitClient) pollDeviceToken(ctx context.Context, body []byte) (token, pending string, err error) {
+	resp, err := c.do(ctx, c.ConduitServer, "POST", "/auth/device/token", body)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", fmt.Errorf("reading device token: Status Code %v returned: %v", resp.StatusCode, err)
+	}
+	switch {
+	case result.AccessToken != "":
+		return result.AccessToken, "", nil
+	case result.Error == "authorization_pending" || result.Error == "slow_down":
+		return "", result.Error, nil
+	case result.Error != "":
+		return "", "", fmt.Errorf("device login failed: %v", result.Error)
+	}
+	return "", "", fmt.Errorf("Status Code %v returned polling device login", resp.StatusCode)
+}
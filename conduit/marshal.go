@@ -0,0 +1,263 @@
+package conduit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalJSON reads LengthOpt and ScaleOpt from numbers, null, or numbers in strings,
+// which some sources send.
+func (c *ColumnStruct) UnmarshalJSON(b []byte) error {
+	type plain ColumnStruct
+	var w struct {
+		plain
+		LengthOpt json.RawMessage `json:"lengthOpt"`
+		ScaleOpt  json.RawMessage `json:"scaleOpt"`
+	}
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	length, err := optFromJson("lengthOpt", w.LengthOpt)
+	if err != nil {
+		return err
+	}
+	scale, err := optFromJson("scaleOpt", w.ScaleOpt)
+	if err != nil {
+		return err
+	}
+	*c = ColumnStruct(w.plain)
+	c.LengthOpt, c.ScaleOpt = length, scale
+	return nil
+}
+
+func optFromJson(name string, raw json.RawMessage) (*int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return &n, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return &n, nil
+		}
+	}
+	return nil, fmt.Errorf("%v should be a whole number or null, not %s", name, raw)
+}
+
+// queryResultWire is QueryResultStruct as Conduit sends it. Data is null until the
+// query has results.
+type queryResultWire struct {
+	QueryId string         `json:"queryId" yaml:"queryId"`
+	Status  string         `json:"status" yaml:"status"`
+	Message *string        `json:"message" yaml:"message"`
+	Data    *queryDataWire `json:"data" yaml:"data"`
+}
+
+type queryDataWire struct {
+	Columns     interface{} `json:"columns" yaml:"columns"`
+	Rows        interface{} `json:"rows" yaml:"rows"`
+	HasNext     bool        `json:"hasNext" yaml:"hasNext"`
+	HasPrevious bool        `json:"hasPrevious" yaml:"hasPrevious"`
+}
+
+// wire takes the columns and rows from ParsedColumns and ParsedRows, or from RawData
+// when they haven't been parsed.
+func (r QueryResultStruct) wire() queryResultWire {
+	w := queryResultWire{QueryId: r.QueryId, Status: r.Status}
+	if r.Message != "" {
+		message := r.Message
+		w.Message = &message
+	}
+	raw := r.RawData
+	if raw.Columns == nil && raw.Rows == nil && r.ParsedColumns == nil && r.ParsedRows == nil && !raw.HasNext && !raw.HasPrevious {
+		return w
+	}
+	w.Data = &queryDataWire{HasNext: raw.HasNext, HasPrevious: raw.HasPrevious}
+	switch {
+	case r.ParsedColumns != nil:
+		w.Data.Columns = r.ParsedColumns
+	case raw.Columns != nil:
+		w.Data.Columns = raw.Columns
+	}
+	switch {
+	case r.ParsedRows != nil:
+		w.Data.Rows = r.ParsedRows
+	case raw.Rows != nil:
+		w.Data.Rows = raw.Rows
+	}
+	return w
+}
+
+// MarshalJSON writes the result as Conduit returns it.
+func (r QueryResultStruct) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.wire())
+}
+
+// UnmarshalJSON reads a result as Conduit returns it, keeping the raw columns and rows
+// and parsing them into ParsedColumns and ParsedRows.
+func (r *QueryResultStruct) UnmarshalJSON(b []byte) error {
+	type plain QueryResultStruct
+	var p plain
+	err := json.Unmarshal(b, &p)
+	*r = QueryResultStruct(p)
+	if parseErr := r.parseData(); err == nil {
+		err = parseErr
+	}
+	return err
+}
+
+// parseData fills ParsedColumns and ParsedRows from RawData. Either may be missing.
+func (r *QueryResultStruct) parseData() error {
+	if r.RawData.Columns != nil {
+		if err := json.Unmarshal(*r.RawData.Columns, &r.ParsedColumns); err != nil {
+			return fmt.Errorf("data.columns: %v", err)
+		}
+	}
+	if r.RawData.Rows != nil {
+		var rows []map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(*r.RawData.Rows))
+		d.UseNumber()
+		if err := d.Decode(&rows); err != nil {
+			return fmt.Errorf("data.rows: %v", err)
+		}
+		for _, row := range rows {
+			r.addRow(row)
+		}
+	}
+	return nil
+}
+
+// MarshalYAML writes the result in the same shape as MarshalJSON.
+func (r QueryResultStruct) MarshalYAML() (interface{}, error) {
+	// RawData can't be written as YAML as it is, so parse whatever hasn't been.
+	parsed := QueryResultStruct{RawData: r.RawData}
+	if err := parsed.parseData(); err != nil {
+		return nil, err
+	}
+	if r.ParsedColumns == nil {
+		r.ParsedColumns = parsed.ParsedColumns
+	}
+	if r.ParsedRows == nil && r.RawData.Rows != nil {
+		r.ParsedRows = parsed.ParsedRows
+		if r.ParsedRows == nil {
+			r.ParsedRows = []map[string]interface{}{}
+		}
+	}
+	w := r.wire()
+	if w.Data != nil {
+		if rows, ok := w.Data.Rows.([]map[string]interface{}); ok {
+			w.Data.Rows = yamlRows(rows)
+		}
+	}
+	return w, nil
+}
+
+// yamlRows copies rows with their json.Numbers as ints or floats, which YAML would
+// otherwise write as quoted strings, including those inside nested values.
+func yamlRows(rows []map[string]interface{}) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		out[i] = yamlNumbers(row).(map[string]interface{})
+	}
+	return out
+}
+
+// yamlNumbers copies v, turning every json.Number in it into an int64 or float64.
+func yamlNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = yamlNumbers(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = yamlNumbers(v)
+		}
+		return s
+	}
+	return v
+}
+
+// UnmarshalYAML reads a result written by MarshalYAML, filling RawData as well as the
+// parsed columns and rows.
+func (r *QueryResultStruct) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var w struct {
+		QueryId string `yaml:"queryId"`
+		Status  string `yaml:"status"`
+		Message string `yaml:"message"`
+		Data    *struct {
+			Columns     []string                 `yaml:"columns"`
+			Rows        []map[string]interface{} `yaml:"rows"`
+			HasNext     bool                     `yaml:"hasNext"`
+			HasPrevious bool                     `yaml:"hasPrevious"`
+		} `yaml:"data"`
+	}
+	if err := unmarshal(&w); err != nil {
+		return err
+	}
+	*r = QueryResultStruct{QueryId: w.QueryId, Status: w.Status, Message: w.Message}
+	if w.Data == nil {
+		return nil
+	}
+	r.RawData.HasNext, r.RawData.HasPrevious = w.Data.HasNext, w.Data.HasPrevious
+	r.ParsedColumns = w.Data.Columns
+	for _, row := range w.Data.Rows {
+		r.ParsedRows = append(r.ParsedRows, stringKeys(row).(map[string]interface{}))
+	}
+	if w.Data.Columns != nil {
+		b, err := json.Marshal(w.Data.Columns)
+		if err != nil {
+			return err
+		}
+		columns := json.RawMessage(b)
+		r.RawData.Columns = &columns
+	}
+	if w.Data.Rows != nil {
+		b, err := json.Marshal(w.Data.Rows)
+		if err != nil {
+			return err
+		}
+		rows := json.RawMessage(b)
+		r.RawData.Rows = &rows
+	}
+	return nil
+}
+
+// stringKeys turns the map[interface{}]interface{} YAML gives nested mappings into
+// map[string]interface{}, as JSON would have given.
+func stringKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = stringKeys(v)
+		}
+		return t
+	case []interface{}:
+		for i, v := range t {
+			t[i] = stringKeys(v)
+		}
+	}
+	return v
+}
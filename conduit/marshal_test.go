@@ -0,0 +1,133 @@
+package conduit
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Payloads as Conduit returns them.
+var wireSamples = []struct {
+	name    string
+	payload string
+	target  interface{}
+}{
+	{"databases", `{"databases":["oracle_flights","sql_synapse_flights"]}`, &DatabasesStruct{}},
+	{"tables", `{"tables":[{"table":"PDBADMIN___FLIGHTS","database":"oracle_flights","schema":"PDBADMIN","tableType":"TABLE"}]}`, &TablesStruct{}},
+	{"table_schema", `{"columns":[{"name":"code","colType":"int","lengthOpt":null,"scaleOpt":null,"sqlType":4},{"name":"fare","colType":"decimal","lengthOpt":10,"scaleOpt":2,"sqlType":3,"nullable":false,"primaryKey":true,"ordinalPosition":2}]}`, &TableSchemaStruct{}},
+	{"query_result", `{"queryId":"q1","status":"Finished","message":null,"data":{"columns":["id","name","fare"],"rows":[{"id":1,"name":"Braund, Mr. Owen Harris","fare":7.25},{"id":2,"name":null,"fare":71.2833}],"hasNext":true,"hasPrevious":false}}`, &QueryResultStruct{}},
+	{"query_running", `{"queryId":"q2","status":"Running","message":null,"data":null}`, &QueryResultStruct{}},
+	{"query_empty", `{"queryId":"q3","status":"Finished","message":"no rows","data":{"columns":[],"rows":[],"hasNext":false,"hasPrevious":false}}`, &QueryResultStruct{}},
+}
+
+// golden compares actual with testdata/name, or rewrites the file when -update is given.
+func golden(t *testing.T, name string, actual []byte) {
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(actual, expected) {
+		t.Errorf("%v\nActual:\n%s\n=====\nExpected:\n%s", name, actual, expected)
+	}
+}
+
+func TestWireFormat_Golden(t *testing.T) {
+	for _, s := range wireSamples {
+		if err := json.Unmarshal([]byte(s.payload), s.target); err != nil {
+			t.Fatalf("%v: %v", s.name, err)
+		}
+		asJson, err := json.MarshalIndent(s.target, "", "  ")
+		if err != nil {
+			t.Fatalf("%v: %v", s.name, err)
+		}
+		asJson = append(asJson, '\n')
+		golden(t, s.name+".golden.json", asJson)
+		asYaml, err := yaml.Marshal(s.target)
+		if err != nil {
+			t.Fatalf("%v: %v", s.name, err)
+		}
+		golden(t, s.name+".golden.yaml", asYaml)
+
+		// Both formats read back to a value that writes them out the same again.
+		fromJson := reflect.New(reflect.TypeOf(s.target).Elem()).Interface()
+		if err := json.Unmarshal(asJson, fromJson); err != nil {
+			t.Fatalf("%v: %v", s.name, err)
+		}
+		if again, _ := json.MarshalIndent(fromJson, "", "  "); !bytes.Equal(append(again, '\n'), asJson) {
+			t.Errorf("%v didn't round-trip through JSON:\n%s", s.name, again)
+		}
+		fromYaml := reflect.New(reflect.TypeOf(s.target).Elem()).Interface()
+		if err := yaml.Unmarshal(asYaml, fromYaml); err != nil {
+			t.Fatalf("%v: %v", s.name, err)
+		}
+		if again, _ := yaml.Marshal(fromYaml); !bytes.Equal(again, asYaml) {
+			t.Errorf("%v didn't round-trip through YAML:\n%s", s.name, again)
+		}
+		if again, _ := json.MarshalIndent(fromYaml, "", "  "); !bytes.Equal(append(again, '\n'), asJson) {
+			t.Errorf("%v read from YAML doesn't write the same JSON:\n%s", s.name, again)
+		}
+	}
+}
+
+func TestColumnStruct_Opts(t *testing.T) {
+	var c ColumnStruct
+	if err := json.Unmarshal([]byte(`{"name":"fare","lengthOpt":10,"scaleOpt":null}`), &c); err != nil || c.LengthOpt == nil || *c.LengthOpt != 10 || c.ScaleOpt != nil {
+		t.Errorf("Actual: %+v (%v)\n=====\nExpected: LengthOpt 10, no ScaleOpt", c, err)
+	}
+	if err := json.Unmarshal([]byte(`{"name":"fare","lengthOpt":"10"}`), &c); err != nil || c.LengthOpt == nil || *c.LengthOpt != 10 {
+		t.Errorf("Actual: %+v (%v)\n=====\nExpected: LengthOpt 10 from a string", c, err)
+	}
+	for _, bad := range []string{`{"lengthOpt":[10]}`, `{"scaleOpt":2.5}`, `{"scaleOpt":"two"}`} {
+		if err := json.Unmarshal([]byte(bad), &c); err == nil {
+			t.Errorf("Expected %v to be refused", bad)
+		}
+	}
+}
+
+func TestQueryResultStruct_MarshalRaw(t *testing.T) {
+	// Results built by hand, with only RawData, still write their rows.
+	columns, rows := json.RawMessage(`["a"]`), json.RawMessage(`[{"a":1}]`)
+	var qrs QueryResultStruct
+	qrs.QueryId = "q1"
+	qrs.RawData.Columns, qrs.RawData.Rows = &columns, &rows
+	actual, err := json.Marshal(qrs)
+	expected := `{"queryId":"q1","status":"","message":null,"data":{"columns":["a"],"rows":[{"a":1}],"hasNext":false,"hasPrevious":false}}`
+	if err != nil || string(actual) != expected {
+		t.Errorf("Actual: %s (%v)\n=====\nExpected: %s", actual, err, expected)
+	}
+}
+
+func TestQueryResultStruct_MarshalYAMLNestedNumbers(t *testing.T) {
+	row := map[string]interface{}{
+		"id":   json.Number("7"),
+		"tags": []interface{}{json.Number("1"), map[string]interface{}{"weight": json.Number("2.5")}},
+	}
+	qrs := QueryResultStruct{ParsedColumns: []string{"id", "tags"}, ParsedRows: []map[string]interface{}{row}}
+	actual, err := yaml.Marshal(qrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"id: 7\n", "- 1\n", "weight: 2.5\n"} {
+		if !strings.Contains(string(actual), expected) {
+			t.Errorf("Actual:\n%s\n=====\nExpected a line %q", actual, expected)
+		}
+	}
+	if _, ok := row["tags"].([]interface{})[0].(json.Number); !ok {
+		t.Errorf("MarshalYAML changed the row it was given")
+	}
+}
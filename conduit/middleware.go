@@ -0,0 +1,88 @@
+package conduit
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// RoundTripFunc sends one request to Conduit and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip makes a RoundTripFunc an http.RoundTripper.
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps every round trip the client makes: metadata calls, query execution,
+// polling and cancels. It may change the request, look at the response, or answer
+// without calling next at all. Responses from next are already decompressed, and
+// limited to MaxResponseSize.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use adds middleware to the client. The first added is the outermost, so it sees each
+// request first and each response last. Each retried attempt passes through the chain again.
+func (c *ConduitClient) Use(mw ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middleware = append(c.middleware, mw...)
+}
+
+// roundTripper builds the middleware chain around HTTPClient, or http.DefaultClient when
+// it's nil.
+func (c *ConduitClient) roundTripper() RoundTripFunc {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	c.mu.Lock()
+	chain := append([]Middleware(nil), c.middleware...)
+	c.mu.Unlock()
+	rt := RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.decompress(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		// Limit what's decompressed, so a small compressed response can't expand without bound.
+		resp.Body = c.limitBody(resp.Body)
+		return resp, nil
+	})
+	for i := len(chain) - 1; i >= 0; i-- {
+		rt = chain[i](rt)
+	}
+	return rt
+}
+
+// WithHeader sets a header on every request, such as a tenant or correlation ID.
+func WithHeader(key, value string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req.Header.Set(key, value)
+			return next(req)
+		}
+	}
+}
+
+// LogRequests logs each request's method, URL, status and duration. The Authorization
+// header is never logged.
+func LogRequests(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.New(log.Writer(), "", log.LstdFlags)
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			if err != nil {
+				logger.Printf("%v %v failed after %v: %v", req.Method, req.URL, time.Since(start), err)
+				return resp, err
+			}
+			logger.Printf("%v %v returned %v in %v", req.Method, req.URL, resp.StatusCode, time.Since(start))
+			return resp, nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package conduit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_Use(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/metadata/databases", server),
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Tenant") != "acme" {
+				return httpmock.NewStringResponse(400, `{"message":"no tenant"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"databases":["db"]}`), nil
+		})
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" in")
+				resp, err := next(req)
+				order = append(order, name+" out")
+				return resp, err
+			}
+		}
+	}
+	var logged bytes.Buffer
+	c.Use(trace("outer"), WithHeader("X-Tenant", "acme"), LogRequests(log.New(&logged, "", 0)))
+	c.Use(trace("inner"))
+
+	dbs := c.GetDatabases()
+	if len(dbs.Databases) != 1 {
+		t.Errorf("Actual: %v\n=====\nExpected: [db]", dbs.Databases)
+	}
+	expected := "[outer in inner in inner out outer out]"
+	if fmt.Sprint(order) != expected {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", order, expected)
+	}
+	if !strings.Contains(logged.String(), "GET https://blah/api/metadata/databases returned 200") || strings.Contains(logged.String(), "blahblah") {
+		t.Errorf("Unexpected log: %v", logged.String())
+	}
+}
+
+func TestConduitClient_UseFault(t *testing.T) {
+	c := NewClient("blah", "blahblah")
+	c.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if strings.Contains(req.URL.Path, "/query/") {
+				return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(`{"message":"injected"}`)), Header: http.Header{}}, nil
+			}
+			return nil, errors.New("unexpected request")
+		}
+	})
+	err := c.ExecuteQuery(context.Background(), "SELECT 1")
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Actual: %v\n=====\nExpected: the injected 503", err)
+	}
+}
@@ -0,0 +1,117 @@
+package conduit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxPageSize is the largest page Conduit returns.
+	MaxPageSize = 1000
+	// DefaultTimeout is how long a query may take when QueryOptions.Timeout is zero.
+	DefaultTimeout = 30 * time.Second
+	// DefaultPollInterval is how long to wait between polls when QueryOptions.PollStrategy is nil.
+	DefaultPollInterval = 2 * time.Second
+)
+
+// ErrQueryTimedOut is returned when a query runs past its Timeout. The query is cancelled
+// on Conduit, and QueryResults holds only the pages fetched before then.
+var ErrQueryTimedOut = errors.New("query timed out")
+
+// PollStrategy decides how long to wait before each poll of a running query. attempt starts at 1.
+type PollStrategy interface {
+	Delay(attempt int) time.Duration
+}
+
+// ConstantPoll waits the same time before every poll.
+type ConstantPoll time.Duration
+
+func (p ConstantPoll) Delay(attempt int) time.Duration {
+	return time.Duration(p)
+}
+
+// BackoffPoll starts at Initial and multiplies the wait by Factor (2 when unset) each poll, up to Max.
+type BackoffPoll struct {
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+}
+
+func (p BackoffPoll) Delay(attempt int) time.Duration {
+	factor := p.Factor
+	if factor <= 1 {
+		factor = 2
+	}
+	d := float64(p.Initial)
+	for i := 1; i < attempt && (p.Max <= 0 || d < float64(p.Max)); i++ {
+		d *= factor
+	}
+	if p.Max > 0 && d > float64(p.Max) {
+		return p.Max
+	}
+	return time.Duration(d)
+}
+
+// QueryOptions tunes one query. The zero value uses the defaults.
+type QueryOptions struct {
+	PageSize     int           // rows per page, up to MaxPageSize; 0 means MaxPageSize
+	MaxRows      int           // stop fetching pages once this many rows are in; 0 fetches everything
+	Limit        int           // wrap the query in a LIMIT so Conduit returns at most this many rows; 0 for none
+	Timeout      time.Duration // total time the query may take before it's cancelled with ErrQueryTimedOut; 0 means DefaultTimeout
+	PollTimeout  time.Duration // time allowed for each poll request; 0 for no limit of its own
+	PollStrategy PollStrategy  // wait between polls, nil means ConstantPoll(DefaultPollInterval)
+	Label        string        // tag carried on the query's events and log lines
+}
+
+// Validate reports the first invalid option.
+func (o QueryOptions) Validate() error {
+	switch {
+	case o.PageSize < 0 || o.PageSize > MaxPageSize:
+		return fmt.Errorf("invalid PageSize %v: must be between 1 and %v, or 0 for the default", o.PageSize, MaxPageSize)
+	case o.MaxRows < 0:
+		return fmt.Errorf("invalid MaxRows %v: must not be negative", o.MaxRows)
+	case o.Limit < 0:
+		return fmt.Errorf("invalid Limit %v: must not be negative", o.Limit)
+	case o.Timeout < 0:
+		return fmt.Errorf("invalid Timeout %v: must not be negative", o.Timeout)
+	case o.PollTimeout < 0:
+		return fmt.Errorf("invalid PollTimeout %v: must not be negative", o.PollTimeout)
+	}
+	return nil
+}
+
+// withDefaults fills in the zero options.
+func (o QueryOptions) withDefaults() QueryOptions {
+	if o.PageSize == 0 {
+		o.PageSize = MaxPageSize
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.PollStrategy == nil {
+		o.PollStrategy = ConstantPoll(DefaultPollInterval)
+	}
+	return o
+}
+
+// NewQueryWithOptions validates opts and sets up a query with them. With opts.Limit
+// set, sqlString is wrapped as a subquery so Conduit applies the LIMIT. Its trailing
+// semicolons are dropped, and the subquery closes on a line of its own so a trailing
+// -- comment doesn't swallow it.
+func NewQueryWithOptions(sqlString string, opts QueryOptions) (QueryStruct, error) {
+	if err := opts.Validate(); err != nil {
+		return QueryStruct{}, err
+	}
+	if opts.Limit > 0 {
+		inner := strings.TrimRight(sqlString, "; \t\r\n")
+		sqlString = fmt.Sprintf("SELECT * FROM (%v\n) AS %v LIMIT %d", inner, QuoteIdentifier("conduit_limited"), opts.Limit)
+	}
+	return QueryStruct{SQLString: sqlString, Options: opts.withDefaults()}, nil
+}
+
+// event starts a QueryEvent carrying the query's ID, status and label.
+func (q *QueryStruct) event(t EventType) QueryEvent {
+	return QueryEvent{Type: t, QueryId: q.ActiveQueryId, Status: q.ActiveQueryStatus, Label: q.Options.Label}
+}
@@ -0,0 +1,155 @@
+package conduit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestQueryOptions_Validate(t *testing.T) {
+	valid := []QueryOptions{
+		{},
+		{PageSize: 1, MaxRows: 10, Limit: 5, Timeout: time.Minute, PollTimeout: time.Second},
+		{PageSize: MaxPageSize},
+	}
+	for _, o := range valid {
+		if err := o.Validate(); err != nil {
+			t.Errorf("Actual: %v\n=====\nExpected: %+v to be valid", err, o)
+		}
+	}
+	invalid := []QueryOptions{
+		{PageSize: -1},
+		{PageSize: MaxPageSize + 1},
+		{MaxRows: -1},
+		{Limit: -1},
+		{Timeout: -time.Second},
+		{PollTimeout: -time.Second},
+	}
+	for _, o := range invalid {
+		if err := o.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", o)
+		}
+	}
+}
+
+func TestNewQueryWithOptions(t *testing.T) {
+	q, err := NewQueryWithOptions("SELECT a FROM t", QueryOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "SELECT * FROM (SELECT a FROM t\n) AS `conduit_limited` LIMIT 10"
+	if q.SQLString != expected {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", q.SQLString, expected)
+	}
+	q, _ = NewQueryWithOptions("SELECT a FROM t -- all of them\n ; ;\n", QueryOptions{Limit: 10})
+	if expected := "SELECT * FROM (SELECT a FROM t -- all of them\n) AS `conduit_limited` LIMIT 10"; q.SQLString != expected {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", q.SQLString, expected)
+	}
+	if q.Options.PageSize != MaxPageSize || q.Options.Timeout != DefaultTimeout || q.Options.PollStrategy == nil {
+		t.Errorf("Defaults not applied: %+v", q.Options)
+	}
+	if _, err := NewQueryWithOptions("SELECT 1", QueryOptions{PageSize: 5000}); err == nil {
+		t.Errorf("Expected an error for PageSize 5000")
+	}
+	if q := NewQuery("SELECT 1", 5000, 0); q.Options.PageSize != MaxPageSize || q.Options.Timeout != 30*time.Second {
+		t.Errorf("NewQuery should still clamp: %+v", q.Options)
+	}
+}
+
+func TestBackoffPoll_Delay(t *testing.T) {
+	p := BackoffPoll{Initial: time.Second, Max: 5 * time.Second}
+	for attempt, expected := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 20: 5 * time.Second} {
+		if d := p.Delay(attempt); d != expected {
+			t.Errorf("Attempt %v. Actual: %v\n=====\nExpected: %v", attempt, d, expected)
+		}
+	}
+}
+
+func TestConduitClient_ExecuteQueryWithOptions(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	var bodies []map[string]interface{}
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		func(req *http.Request) (*http.Response, error) {
+			raw, _ := ioutil.ReadAll(req.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(raw, &body)
+			bodies = append(bodies, body)
+			if len(bodies) == 1 {
+				return httpmock.NewStringResponse(200, `{"queryId":"q1","status":"Running","message":null,"data":null}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"queryId":"q1","status":"ResultsReady","message":null,"data":{"columns":["a"],"rows":[{"a":3},{"a":4}],"hasNext":true,"hasPrevious":true}}`), nil
+		})
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/execute/q1/result", server),
+		httpmock.NewStringResponder(200, `{"queryId":"q1","status":"ResultsReady","message":null,"data":{"columns":["a"],"rows":[{"a":1},{"a":2}],"hasNext":true,"hasPrevious":false}}`))
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	var labels []string
+	c.OnEvent(func(e QueryEvent) { labels = append(labels, e.Label) })
+	err := c.ExecuteQueryWithOptions(context.Background(), QueryOptions{
+		PageSize:     2,
+		MaxRows:      3,
+		PollStrategy: ConstantPoll(time.Millisecond),
+		Label:        "nightly",
+	}, "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("ExecuteQueryWithOptions failed: %v", err)
+	}
+	rows := 0
+	for _, v := range c.Query.QueryResults {
+		rows += len(v.ParsedRows)
+	}
+	if rows != 3 || len(c.Query.QueryResults) != 2 {
+		t.Errorf("Actual: %v rows in %v pages\n=====\nExpected: 3 rows in 2 pages", rows, len(c.Query.QueryResults))
+	}
+	if len(bodies) != 2 || bodies[0]["pageSize"] != float64(2) || bodies[1]["queryId"] != "q1" {
+		t.Errorf("Unexpected request bodies: %v", bodies)
+	}
+	if c.Query.ActiveQueryStatus != "Cancelled" {
+		t.Errorf("Actual: %v\n=====\nExpected: the query cancelled after MaxRows", c.Query.ActiveQueryStatus)
+	}
+	for _, l := range labels {
+		if l != "nightly" {
+			t.Errorf("Actual: %q\n=====\nExpected: every event labelled nightly", l)
+		}
+	}
+
+	if err := c.ExecuteQueryWithOptions(context.Background(), QueryOptions{PageSize: -5}, "SELECT 1"); err == nil {
+		t.Errorf("Expected an error for a negative PageSize")
+	}
+}
+
+func TestConduitClient_ExecuteQueryWithOptions_Timeout(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	running := `{"queryId":"q1","status":"Running","message":null,"data":null}`
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server), httpmock.NewStringResponder(200, running))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/execute/q1/result", server), httpmock.NewStringResponder(200, running))
+	var cancels int
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server), func(req *http.Request) (*http.Response, error) {
+		cancels++
+		return httpmock.NewStringResponse(200, `{"isCancelled":true}`), nil
+	})
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	err := c.ExecuteQueryWithOptions(context.Background(), QueryOptions{
+		Timeout:      30 * time.Millisecond,
+		PollStrategy: ConstantPoll(5 * time.Millisecond),
+	}, "SELECT a FROM t")
+	if !errors.Is(err, ErrQueryTimedOut) || cancels != 1 {
+		t.Errorf("Actual: %v after %v cancels\n=====\nExpected: %v after cancelling once", err, cancels, ErrQueryTimedOut)
+	}
+}
@@ -0,0 +1,166 @@
+package conduit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamedArg is a value for a :name placeholder.
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named binds value to the :name placeholder.
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// Bind replaces the placeholders in sqlString with args rendered by QuoteValue.
+// Placeholders are either positional ?, filled from args in order, or named :name,
+// filled from Named args or from a single map[string]interface{}. The two styles
+// can't be mixed. Placeholders inside quoted strings, backtick identifiers and
+// comments are left alone, as is a :: cast. With no args sqlString is returned as is.
+//
+// Conduit takes the query as one SQL string, so the values are escaped client-side.
+func Bind(sqlString string, args ...interface{}) (string, error) {
+	if len(args) == 0 {
+		return sqlString, nil
+	}
+	named, err := namedArgs(args)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	next := 0
+	used := map[string]bool{}
+	for i := 0; i < len(sqlString); i++ {
+		ch := sqlString[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := skipQuoted(sqlString, i)
+			b.WriteString(sqlString[i:end])
+			i = end - 1
+		case ch == '-' && strings.HasPrefix(sqlString[i:], "--"):
+			end := strings.IndexByte(sqlString[i:], '\n')
+			if end < 0 {
+				end = len(sqlString) - i
+			}
+			b.WriteString(sqlString[i : i+end])
+			i += end - 1
+		case ch == '/' && strings.HasPrefix(sqlString[i:], "/*"):
+			end := strings.Index(sqlString[i+2:], "*/")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated comment in %q", sqlString)
+			}
+			b.WriteString(sqlString[i : i+end+4])
+			i += end + 3
+		case ch == ':' && i+1 < len(sqlString) && sqlString[i+1] == ':':
+			b.WriteString("::")
+			i++
+		case ch == ':' && i+1 < len(sqlString) && isIdentStart(sqlString[i+1]) && (i == 0 || !isIdentPart(sqlString[i-1])):
+			end := i + 1
+			for end < len(sqlString) && isIdentPart(sqlString[end]) {
+				end++
+			}
+			name := sqlString[i+1 : end]
+			if named == nil {
+				return "", fmt.Errorf("placeholder :%v needs named arguments", name)
+			}
+			value, ok := named[name]
+			if !ok {
+				return "", fmt.Errorf("no argument named %q", name)
+			}
+			literal, err := QuoteValue(value)
+			if err != nil {
+				return "", fmt.Errorf("argument %v: %v", name, err)
+			}
+			b.WriteString(literal)
+			used[name] = true
+			i = end - 1
+		case ch == '?':
+			if named != nil {
+				return "", fmt.Errorf("positional ? placeholder used with named arguments")
+			}
+			if next >= len(args) {
+				return "", fmt.Errorf("not enough arguments: %v given", len(args))
+			}
+			literal, err := QuoteValue(args[next])
+			if err != nil {
+				return "", fmt.Errorf("argument %v: %v", next+1, err)
+			}
+			b.WriteString(literal)
+			next++
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	if named != nil {
+		for name := range named {
+			if !used[name] {
+				return "", fmt.Errorf("argument %q is not used in the query", name)
+			}
+		}
+	} else if next != len(args) {
+		return "", fmt.Errorf("%v arguments given for %v placeholders", len(args), next)
+	}
+	return b.String(), nil
+}
+
+// namedArgs returns the named values in args, or nil when args are positional.
+func namedArgs(args []interface{}) (map[string]interface{}, error) {
+	if m, ok := args[0].(map[string]interface{}); ok {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("a map of named arguments must be the only argument")
+		}
+		return m, nil
+	}
+	if _, ok := args[0].(NamedArg); !ok {
+		for _, a := range args {
+			if _, ok := a.(NamedArg); ok {
+				return nil, fmt.Errorf("named and positional arguments can't be mixed")
+			}
+		}
+		return nil, nil
+	}
+	named := map[string]interface{}{}
+	for _, a := range args {
+		n, ok := a.(NamedArg)
+		if !ok {
+			return nil, fmt.Errorf("named and positional arguments can't be mixed")
+		}
+		if _, dup := named[n.Name]; dup {
+			return nil, fmt.Errorf("argument %q given twice", n.Name)
+		}
+		named[n.Name] = n.Value
+	}
+	return named, nil
+}
+
+// skipQuoted returns the index just past the quoted section starting at start.
+func skipQuoted(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || (ch >= '0' && ch <= '9')
+}
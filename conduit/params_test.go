@@ -0,0 +1,74 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestBind(t *testing.T) {
+	cases := []struct {
+		sql      string
+		args     []interface{}
+		expected string
+	}{
+		{"SELECT * FROM t WHERE a = ? AND b IN ?", []interface{}{"x'y", []int{1, 2}}, "SELECT * FROM t WHERE a = 'x''y' AND b IN (1, 2)"},
+		{"SELECT '?', `a?` FROM t WHERE x = ? -- ?\n", []interface{}{nil}, "SELECT '?', `a?` FROM t WHERE x = NULL -- ?\n"},
+		{"SELECT * FROM t WHERE a = :a AND b = :b::int", []interface{}{Named("a", true), Named("b", 2.5)}, "SELECT * FROM t WHERE a = TRUE AND b = 2.5::int"},
+		{"SELECT * FROM t WHERE a = :a OR c = :a", []interface{}{map[string]interface{}{"a": "z"}}, "SELECT * FROM t WHERE a = 'z' OR c = 'z'"},
+		{"SELECT '10:30' AS t, ? AS q", nil, "SELECT '10:30' AS t, ? AS q"},
+	}
+	for _, c := range cases {
+		actual, err := Bind(c.sql, c.args...)
+		if err != nil {
+			t.Errorf("Bind(%q) failed: %v", c.sql, err)
+		} else if actual != c.expected {
+			t.Errorf("Actual: \n%s\n=====\nExpected:\n%s", actual, c.expected)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	cases := map[string][]interface{}{
+		"x = ? AND y = ?": {1},
+		"x = ?":           {1, 2},
+		"x = :a":          {Named("b", 1)},
+		"x = :a AND ?":    {Named("a", 1)},
+		"x = :a AND y=?":  {Named("a", 1), 2},
+		"x = ?  ":         {func() {}},
+	}
+	for sql, args := range cases {
+		if _, err := Bind(sql, args...); err == nil {
+			t.Errorf("Bind(%q, %v) should have failed", sql, args)
+		}
+	}
+}
+
+func TestConduitClient_ExecuteQueryBindsArgs(t *testing.T) {
+	var posted string
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	url := fmt.Sprintf("https://%v/api/query/execute", viper.GetString("CONDUIT_SERVER"))
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		posted = string(body)
+		return httpmock.NewStringResponse(200, `{"queryId":"q1","status":"Finished","message":null,"data":{"columns":["Name"],"rows":[{"Name":"O'Brien"}],"hasNext":false,"hasPrevious":false}}`), nil
+	})
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	err := c.ExecuteQuery(context.Background(), "SELECT * FROM `db`.`t` WHERE Name = ?", "O'Brien")
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if !strings.Contains(posted, `WHERE Name = 'O''Brien'`) {
+		t.Errorf("Posted body doesn't have the bound value: %v", posted)
+	}
+	if len(c.Query.QueryResults) != 1 || len(c.Query.QueryResults[0].ParsedRows) != 1 {
+		t.Errorf("Expected one page with one row, got %+v", c.Query.QueryResults)
+	}
+}
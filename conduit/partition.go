@@ -0,0 +1,427 @@
+package conduit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Range is a half-open [Low, High) bound on the partition column. A nil Low or High leaves that side open.
+type Range struct {
+	Low, High interface{}
+}
+
+// ExtractOptions describes a partitioned extract of one table.
+type ExtractOptions struct {
+	Database string
+	Table    string
+	Columns  []string // defaults to every column
+
+	// Column is the numeric or date column the table is split on. The range from
+	// Min to Max is cut into Partitions equal slices. When Min or Max is nil it is
+	// looked up with a MIN/MAX query. Ranges overrides all of this with explicit bounds.
+	Column     string
+	Partitions int
+	Min, Max   interface{}
+	Ranges     []Range
+
+	Concurrency int  // partition queries run at once, defaults to 4
+	Retries     int  // extra attempts per partition after a failure
+	Ordered     bool // deliver partitions in order, rather than as they finish
+
+	// Query overrides the client's Options for the partition and MIN/MAX queries.
+	Query *QueryOptions
+}
+
+func (c *ConduitClient) extractQuery(opts ExtractOptions, sqlString string) (QueryStruct, error) {
+	if opts.Query != nil {
+		return NewQueryWithOptions(sqlString, *opts.Query)
+	}
+	return NewQueryWithOptions(sqlString, c.Options)
+}
+
+// Partition is one slice of an extract.
+type Partition struct {
+	Index     int
+	Condition string
+	SQLString string
+	Attempt   int // 1 on the first try, counting up as the partition is retried
+}
+
+// PartitionSink receives each page of a partition's rows. It's never called concurrently.
+// When a partition is retried its pages are delivered again from the first, with a higher
+// Attempt, so a sink should drop what it has of that partition when Attempt changes.
+// Returning an error stops the extract.
+type PartitionSink func(p Partition, rows []map[string]interface{}) error
+
+// Partitions works out the partition queries for opts without running them.
+func (c *ConduitClient) Partitions(ctx context.Context, opts ExtractOptions) ([]Partition, error) {
+	if opts.Database == "" || opts.Table == "" || opts.Column == "" {
+		return nil, fmt.Errorf("a partitioned extract needs a Database, Table and Column")
+	}
+	ranges := opts.Ranges
+	if len(ranges) == 0 {
+		if opts.Partitions < 1 {
+			return nil, fmt.Errorf("a partitioned extract needs Partitions or Ranges")
+		}
+		lo, hi := opts.Min, opts.Max
+		if lo == nil || hi == nil {
+			foundLo, foundHi, err := c.columnBounds(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			if lo == nil {
+				lo = foundLo
+			}
+			if hi == nil {
+				hi = foundHi
+			}
+		}
+		var err error
+		ranges, err = SplitRange(lo, hi, opts.Partitions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	column := QuoteIdentifier(opts.Column)
+	var partitions []Partition
+	for i, r := range ranges {
+		var condition string
+		var args []interface{}
+		if r.Low != nil {
+			condition = column + " >= ?"
+			args = append(args, r.Low)
+		}
+		if r.High != nil {
+			if condition != "" {
+				condition += " AND "
+			}
+			condition += column + " < ?"
+			args = append(args, r.High)
+		}
+		if i == 0 && len(opts.Ranges) == 0 {
+			// Rows with a NULL partition column fall outside every range, so the first partition takes them.
+			condition = "(" + condition + ") OR " + column + " IS NULL"
+		}
+		condition, err := Bind(condition, args...)
+		if err != nil {
+			return nil, err
+		}
+		b := Select(opts.Columns...).From(opts.Database, opts.Table)
+		if condition != "" {
+			b.WhereRaw(condition)
+		}
+		sqlString, err := b.Build()
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, Partition{Index: i, Condition: condition, SQLString: sqlString})
+	}
+	return partitions, nil
+}
+
+// columnBounds looks up MIN and MAX of the partition column.
+func (c *ConduitClient) columnBounds(ctx context.Context, opts ExtractOptions) (interface{}, interface{}, error) {
+	column := QuoteIdentifier(opts.Column)
+	q, err := c.extractQuery(opts, fmt.Sprintf("SELECT MIN(%v) AS lo, MAX(%v) AS hi FROM %v",
+		column, column, QuoteTable(opts.Database, opts.Table)))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.run(ctx, &q); err != nil {
+		return nil, nil, err
+	}
+	if len(q.QueryResults) == 0 || len(q.QueryResults[0].ParsedRows) == 0 {
+		return nil, nil, fmt.Errorf("no MIN/MAX returned for %v", opts.Column)
+	}
+	// exactRow keeps integer bounds past 2^53 as json.Numbers rather than rounded float64s.
+	row := q.QueryResults[0].exactRow(0)
+	return boundValue(row["lo"]), boundValue(row["hi"]), nil
+}
+
+// boundValue turns a date string from a MIN/MAX row into a time.Time, leaving numbers alone.
+func boundValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t
+			}
+		}
+	}
+	return v
+}
+
+// SplitRange cuts [lo, hi] into n consecutive ranges. lo and hi must both be numbers,
+// or both be time.Time. The last range's High is just past hi, so hi itself is included.
+// Whole number bounds, including json.Numbers, are split exactly as int64s, and the
+// last High is left open when hi is the largest int64.
+func SplitRange(lo, hi interface{}, n int) ([]Range, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("cannot split into %v partitions", n)
+	}
+	if loTime, ok := lo.(time.Time); ok {
+		hiTime, ok := hi.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("bounds %v and %v are not both times", lo, hi)
+		}
+		step := hiTime.Sub(loTime) / time.Duration(n)
+		if step <= 0 {
+			step = 1
+		}
+		var ranges []Range
+		for i := 0; i < n; i++ {
+			high := loTime.Add(step * time.Duration(i+1))
+			if i == n-1 {
+				high = hiTime.Add(time.Microsecond)
+			}
+			ranges = append(ranges, Range{Low: loTime.Add(step * time.Duration(i)), High: high})
+		}
+		return ranges, nil
+	}
+	loInt, ok1 := toInt(lo)
+	hiInt, ok2 := toInt(hi)
+	if ok1 && ok2 {
+		return splitInts(loInt, hiInt, n)
+	}
+	loNum, ok1 := toFloat(lo)
+	hiNum, ok2 := toFloat(hi)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("bounds %v and %v are not both numbers or times", lo, hi)
+	}
+	if hiNum < loNum {
+		return nil, fmt.Errorf("min %v is above max %v", lo, hi)
+	}
+	var ranges []Range
+	step := (hiNum - loNum) / float64(n)
+	for i := 0; i < n; i++ {
+		high := loNum + step*float64(i+1)
+		if i == n-1 {
+			high = math.Nextafter(hiNum, math.Inf(1))
+		}
+		ranges = append(ranges, Range{Low: loNum + step*float64(i), High: high})
+	}
+	return ranges, nil
+}
+
+// splitInts works in big.Int so neither the step nor the last High can overflow int64.
+func splitInts(lo, hi *big.Int, n int) ([]Range, error) {
+	if hi.Cmp(lo) < 0 {
+		return nil, fmt.Errorf("min %v is above max %v", lo, hi)
+	}
+	if !lo.IsInt64() || !hi.IsInt64() {
+		return nil, fmt.Errorf("bounds %v and %v are outside the int64 range", lo, hi)
+	}
+	step := new(big.Int).Sub(hi, lo)
+	step.Add(step, big.NewInt(int64(n)))
+	step.Quo(step, big.NewInt(int64(n)))
+	var ranges []Range
+	for start := lo; start.Cmp(hi) <= 0; {
+		next := new(big.Int).Add(start, step)
+		r := Range{Low: start.Int64()}
+		if next.IsInt64() {
+			r.High = next.Int64()
+		}
+		ranges = append(ranges, r)
+		start = next
+	}
+	return ranges, nil
+}
+
+// toInt returns v as an integer when it's a whole number, reading json.Numbers exactly.
+func toInt(v interface{}) (*big.Int, bool) {
+	switch t := v.(type) {
+	case int:
+		return big.NewInt(int64(t)), true
+	case int32:
+		return big.NewInt(int64(t)), true
+	case int64:
+		return big.NewInt(t), true
+	case json.Number:
+		return new(big.Int).SetString(string(t), 10)
+	}
+	if f, ok := toFloat(v); ok && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return big.NewInt(int64(f)), true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+// ExtractPartitioned runs one query per partition, at most opts.Concurrency at a time,
+// and hands each page of a partition's rows to sink as it arrives. A failed partition,
+// including one that runs past its Timeout, is retried opts.Retries times. The first
+// error that outlasts its retries cancels the rest of the extract.
+func (c *ConduitClient) ExtractPartitioned(ctx context.Context, opts ExtractOptions, sink PartitionSink) error {
+	partitions, err := c.Partitions(ctx, opts)
+	if err != nil {
+		return err
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 4
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		turn     = sync.NewCond(&mu) // signalled when nextOut moves or the extract stops
+		firstErr error
+		nextOut  = 0
+		wg       sync.WaitGroup
+		slots    = make(chan struct{}, concurrency)
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		turn.Broadcast()
+	}
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		turn.Broadcast()
+		mu.Unlock()
+	}()
+	// waitTurn holds mu until it's p's turn to be delivered, which in Ordered mode is
+	// after every earlier partition. Partitions start in order, so the one whose turn it
+	// is always has a slot.
+	waitTurn := func(p Partition) error {
+		for opts.Ordered && nextOut != p.Index && firstErr == nil && ctx.Err() == nil {
+			turn.Wait()
+		}
+		if firstErr != nil {
+			return firstErr
+		}
+		return ctx.Err()
+	}
+	deliver := func(p Partition, rows []map[string]interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := waitTurn(p); err != nil {
+			return err
+		}
+		if err := sink(p, rows); err != nil {
+			return sinkError{err}
+		}
+		return nil
+	}
+
+	for _, p := range partitions {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(p Partition) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			err := c.extractPartition(ctx, p, opts, deliver)
+			mu.Lock()
+			defer mu.Unlock()
+			if e, ok := err.(sinkError); ok {
+				fail(e.err)
+				return
+			}
+			if err != nil {
+				fail(fmt.Errorf("partition %v: %w", p.Index, err))
+				return
+			}
+			if waitTurn(p) == nil && opts.Ordered {
+				nextOut++
+				turn.Broadcast()
+			}
+		}(p)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// sinkError carries an error from the sink out of the partition's query, so it isn't retried.
+type sinkError struct {
+	err error
+}
+
+func (e sinkError) Error() string {
+	return e.err.Error()
+}
+
+func (c *ConduitClient) extractPartition(ctx context.Context, p Partition, opts ExtractOptions, deliver func(Partition, []map[string]interface{}) error) error {
+	q, err := c.extractQuery(opts, p.SQLString)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; attempt <= opts.Retries+1; attempt++ {
+		if attempt > 1 {
+			log.Printf("Retrying partition %v (attempt %v) after: %v", p.Index, attempt, err)
+			e := q.event(EventRetrying)
+			e.Attempt, e.Err = attempt, err
+			c.emit(e)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt-1) * time.Second):
+			}
+		}
+		p.Attempt = attempt
+		q = QueryStruct{SQLString: q.SQLString, Options: q.Options}
+		// Rows are collected straight from the decoder into the page for the sink, rather
+		// than into ParsedRows, and let go once the sink has them.
+		var page []map[string]interface{}
+		q.onRow = func(columns []string, row map[string]interface{}) error {
+			page = append(page, row)
+			return nil
+		}
+		q.onPage = func(qrs QueryResultStruct) error {
+			rows := page
+			page = nil
+			waiting := time.Now()
+			err := deliver(p, rows)
+			// Time spent waiting for the sink, or for earlier partitions, isn't time the
+			// query ran on Conduit, so it doesn't count toward the Timeout.
+			q.StartTime = q.StartTime.Add(time.Since(waiting))
+			return err
+		}
+		err = c.run(ctx, &q)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(sinkError); ok {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package conduit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestSplitRange(t *testing.T) {
+	ranges, err := SplitRange(float64(1), float64(10), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Range{{int64(1), int64(5)}, {int64(5), int64(9)}, {int64(9), int64(13)}}
+	if fmt.Sprint(ranges) != fmt.Sprint(expected) {
+		t.Errorf("Actual: %v Expected: %v", ranges, expected)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ranges, err = SplitRange(start, start.Add(48*time.Hour), 2)
+	if err != nil || len(ranges) != 2 || ranges[1].Low != start.Add(24*time.Hour) {
+		t.Errorf("Unexpected time ranges: %v (%v)", ranges, err)
+	}
+	if _, err := SplitRange("a", 1, 2); err == nil {
+		t.Errorf("Expected an error for non-numeric bounds")
+	}
+}
+
+func TestSplitRange_LargeIntegers(t *testing.T) {
+	ranges, err := SplitRange(json.Number("9007199254740993"), json.Number("9007199254740996"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Range{{int64(9007199254740993), int64(9007199254740995)}, {int64(9007199254740995), int64(9007199254740997)}}
+	if fmt.Sprint(ranges) != fmt.Sprint(expected) {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", ranges, expected)
+	}
+	ranges, err = SplitRange(int64(math.MaxInt64-3), int64(math.MaxInt64), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Range{{int64(math.MaxInt64 - 3), int64(math.MaxInt64 - 1)}, {int64(math.MaxInt64 - 1), nil}}
+	if fmt.Sprint(ranges) != fmt.Sprint(expected) {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", ranges, expected)
+	}
+	ranges, err = SplitRange(json.Number("0.5"), json.Number("2.5"), 2)
+	if err != nil || len(ranges) != 2 || ranges[1].Low != 1.5 {
+		t.Errorf("Unexpected decimal ranges: %v (%v)", ranges, err)
+	}
+}
+
+func TestConduitClient_ExtractPartitioned(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	var failures int32
+	url := fmt.Sprintf("https://%v/api/query/execute", viper.GetString("CONDUIT_SERVER"))
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		if strings.Contains(body.Query, ">= 6 AND") && atomic.AddInt32(&failures, 1) == 1 {
+			return httpmock.NewStringResponse(500, `{"message":"flaky"}`), nil
+		}
+		rows := `[{"id":1}]`
+		if !strings.Contains(body.Query, ">= 1 AND") {
+			rows = `[{"id":2},{"id":3}]`
+		}
+		return httpmock.NewStringResponse(200, `{"queryId":"q","status":"Finished","data":{"columns":["id"],"rows":`+rows+`,"hasNext":false}}`), nil
+	})
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	var order []int
+	total := 0
+	err := c.ExtractPartitioned(context.Background(), ExtractOptions{
+		Database: "oracle_flights", Table: "PDBADMIN___FLIGHTS", Column: "id",
+		Min: 1, Max: 9, Partitions: 2, Concurrency: 2, Retries: 1, Ordered: true,
+	}, func(p Partition, rows []map[string]interface{}) error {
+		order = append(order, p.Index)
+		total += len(rows)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExtractPartitioned failed: %v", err)
+	}
+	if fmt.Sprint(order) != "[0 1]" || total != 3 || failures != 2 {
+		t.Errorf("Actual order %v with %v rows after %v tries, Expected [0 1] with 3 rows after 2", order, total, failures)
+	}
+}
+
+func TestConduitClient_Partitions(t *testing.T) {
+	c := NewClient("blah", "blahblah")
+	partitions, err := c.Partitions(context.Background(), ExtractOptions{
+		Database: "db", Table: "t", Column: "id", Columns: []string{"id"},
+		Ranges: []Range{{nil, 10}, {10, nil}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partitions[0].SQLString != "SELECT `id` FROM `db`.`t` WHERE (`id` < 10)" ||
+		partitions[1].SQLString != "SELECT `id` FROM `db`.`t` WHERE (`id` >= 10)" {
+		t.Errorf("Unexpected partition queries: %+v", partitions)
+	}
+}
+
+func TestConduitClient_ExtractPartitioned_Timeout(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	var starts int32
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server), func(req *http.Request) (*http.Response, error) {
+		var body struct {
+			QueryId *string `json:"queryId"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		switch {
+		case body.QueryId == nil && atomic.AddInt32(&starts, 1) == 1:
+			// The first try never finishes.
+			return httpmock.NewStringResponse(200, `{"queryId":"slow","status":"Running","data":null}`), nil
+		case body.QueryId == nil:
+			return httpmock.NewStringResponse(200, `{"queryId":"fast","status":"ResultsReady","data":{"columns":["id"],"rows":[{"id":1}],"hasNext":true}}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"queryId":"fast","status":"Finished","data":{"columns":["id"],"rows":[{"id":2},{"id":3}],"hasNext":false}}`), nil
+	})
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/execute/slow/result", server),
+		httpmock.NewStringResponder(200, `{"queryId":"slow","status":"Running","data":null}`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=slow", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	opts := ExtractOptions{
+		Database: "oracle_flights", Table: "PDBADMIN___FLIGHTS", Column: "id", Min: 1, Max: 9, Partitions: 1,
+		Query: &QueryOptions{Timeout: 50 * time.Millisecond, PollStrategy: ConstantPoll(10 * time.Millisecond)},
+	}
+	var pages []string
+	sink := func(p Partition, rows []map[string]interface{}) error {
+		pages = append(pages, fmt.Sprintf("attempt %v: %v rows", p.Attempt, len(rows)))
+		return nil
+	}
+	if err := c.ExtractPartitioned(context.Background(), opts, sink); !errors.Is(err, ErrQueryTimedOut) || len(pages) != 0 {
+		t.Errorf("Actual: %v, pages %v\n=====\nExpected: %v with nothing delivered", err, pages, ErrQueryTimedOut)
+	}
+
+	atomic.StoreInt32(&starts, 0)
+	opts.Retries = 1
+	if err := c.ExtractPartitioned(context.Background(), opts, sink); err != nil {
+		t.Fatalf("ExtractPartitioned failed: %v", err)
+	}
+	if fmt.Sprint(pages) != "[attempt 2: 1 rows attempt 2: 2 rows]" {
+		t.Errorf("Actual: %v\n=====\nExpected: each page of the retried partition", pages)
+	}
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	conduit "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+)
+
+// benchSizes match the synthetic pages of the conduit package's benchmarks.
+var benchSizes = []struct {
+	rows, columns int
+}{
+	{10, 10},
+	{100, 20},
+	{1000, 20},
+	{1000, 200},
+}
+
+// syntheticResult is a parsed page with columns c000, c001... of, in turn, an integer,
+// a decimal, a string, and a string that is null every other row.
+func syntheticResult(rows, columns int) conduit.QueryResultStruct {
+	var qrs conduit.QueryResultStruct
+	qrs.QueryId, qrs.Status = "bench", "Finished"
+	for c := 0; c < columns; c++ {
+		qrs.ParsedColumns = append(qrs.ParsedColumns, fmt.Sprintf("c%03d", c))
+	}
+	for r := 0; r < rows; r++ {
+		row := make(map[string]interface{}, columns)
+		for c, name := range qrs.ParsedColumns {
+			switch c % 4 {
+			case 0:
+				row[name] = float64(r*columns + c)
+			case 1:
+				row[name] = float64(r) + float64(c%100)/100
+			case 2:
+				row[name] = fmt.Sprintf("row %d, column %d", r, c)
+			default:
+				if r%2 == 0 {
+					row[name] = nil
+				} else {
+					row[name] = strings.Repeat("x", c%32)
+				}
+			}
+		}
+		qrs.ParsedRows = append(qrs.ParsedRows, row)
+	}
+	return qrs
+}
+
+func BenchmarkResults(b *testing.B) {
+	for _, size := range benchSizes {
+		qrs := syntheticResult(size.rows, size.columns)
+		b.Run(fmt.Sprintf("%vx%v", size.rows, size.columns), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Results(qrs)
+			}
+		})
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	for _, size := range benchSizes {
+		table := Results(syntheticResult(size.rows, size.columns))
+		for _, format := range Formats {
+			b.Run(fmt.Sprintf("%vx%v/%v", size.rows, size.columns, format), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := Render(ioutil.Discard, table, Options{Format: format, MaxColumnWidth: 40}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package render
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"html"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v2"
+)
+
+// box is the set of characters a grid is drawn with.
+type box struct {
+	horizontal, vertical                         string
+	topLeft, topMid, topRight                    string
+	midLeft, midMid, midRight                    string
+	bottomLeft, bottomMid, bottomRight, ellipsis string
+}
+
+var (
+	asciiBox   = box{"-", "|", "+", "+", "+", "+", "+", "+", "+", "+", "+", "..."}
+	unicodeBox = box{"─", "│", "┌", "┬", "┐", "├", "┼", "┤", "└", "┴", "┘", "…"}
+)
+
+// cells formats every value, truncated to MaxColumnWidth, and says which columns are numeric.
+func cells(t Table, opts Options, ellipsis string, null string) (header []string, rows [][]string, numeric []bool) {
+	header = make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = truncate(oneLine(c), opts.MaxColumnWidth, ellipsis)
+	}
+	numeric = make([]bool, len(t.Columns))
+	for i := range numeric {
+		numeric[i] = len(t.Rows) > 0
+	}
+	for _, r := range t.Rows {
+		row := make([]string, len(t.Columns))
+		for i := range t.Columns {
+			var v interface{}
+			if i < len(r) {
+				v = r[i]
+			}
+			s, ok := text(v)
+			if !ok {
+				s = null
+			} else if !isNumber(v) {
+				numeric[i] = false
+			}
+			row[i] = truncate(oneLine(s), opts.MaxColumnWidth, ellipsis)
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, numeric
+}
+
+func nullOr(opts Options, fallback string) string {
+	if opts.Null != "" {
+		return opts.Null
+	}
+	return fallback
+}
+
+func writeGrid(w io.Writer, t Table, opts Options, b box) error {
+	header, rows, numeric := cells(t, opts, b.ellipsis, nullOr(opts, "NULL"))
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, r := range rows {
+		for i, c := range r {
+			if n := utf8.RuneCountInString(c); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	maxWidth := opts.MaxWidth
+	if maxWidth == 0 {
+		maxWidth = TerminalWidth(w)
+	}
+	fit(widths, maxWidth)
+
+	out := bufio.NewWriter(w)
+	rule := func(left, mid, right string) {
+		out.WriteString(left)
+		for i, width := range widths {
+			if i > 0 {
+				out.WriteString(mid)
+			}
+			out.WriteString(strings.Repeat(b.horizontal, width+2))
+		}
+		out.WriteString(right + "\n")
+	}
+	line := func(values []string, align bool) {
+		out.WriteString(b.vertical)
+		for i, v := range values {
+			v = truncate(v, widths[i], b.ellipsis)
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v))
+			if align && numeric[i] {
+				out.WriteString(" " + pad + v + " " + b.vertical)
+			} else {
+				out.WriteString(" " + v + pad + " " + b.vertical)
+			}
+		}
+		out.WriteString("\n")
+	}
+	rule(b.topLeft, b.topMid, b.topRight)
+	line(header, false)
+	rule(b.midLeft, b.midMid, b.midRight)
+	for _, r := range rows {
+		line(r, true)
+	}
+	rule(b.bottomLeft, b.bottomMid, b.bottomRight)
+	return out.Flush()
+}
+
+// fit narrows the widest columns until the table, with its borders and padding, is no
+// wider than max. No column goes below 3 characters.
+func fit(widths []int, max int) {
+	if max <= 0 {
+		return
+	}
+	total := func() int {
+		sum := 1
+		for _, w := range widths {
+			sum += w + 3
+		}
+		return sum
+	}
+	for total() > max {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= 3 {
+			return
+		}
+		widths[widest]--
+	}
+}
+
+func writeMarkdown(w io.Writer, t Table, opts Options) error {
+	header, rows, numeric := cells(t, opts, "...", nullOr(opts, "NULL"))
+	escape := strings.NewReplacer("|", `\|`)
+	out := bufio.NewWriter(w)
+	line := func(values []string) {
+		out.WriteString("|")
+		for _, v := range values {
+			out.WriteString(" " + escape.Replace(v) + " |")
+		}
+		out.WriteString("\n")
+	}
+	line(header)
+	out.WriteString("|")
+	for i := range header {
+		if numeric[i] {
+			out.WriteString(" ---: |")
+		} else {
+			out.WriteString(" --- |")
+		}
+	}
+	out.WriteString("\n")
+	for _, r := range rows {
+		line(r)
+	}
+	return out.Flush()
+}
+
+func writeHTML(w io.Writer, t Table, opts Options) error {
+	null := nullOr(opts, "NULL")
+	out := bufio.NewWriter(w)
+	out.WriteString("<table>\n<thead>\n<tr>")
+	for _, c := range t.Columns {
+		out.WriteString("<th>" + html.EscapeString(truncate(c, opts.MaxColumnWidth, "…")) + "</th>")
+	}
+	out.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, r := range t.Rows {
+		out.WriteString("<tr>")
+		for i := range t.Columns {
+			var v interface{}
+			if i < len(r) {
+				v = r[i]
+			}
+			s, ok := text(v)
+			switch {
+			case !ok:
+				out.WriteString(`<td class="null">` + html.EscapeString(null) + "</td>")
+			case isNumber(v):
+				out.WriteString(`<td class="number">` + html.EscapeString(s) + "</td>")
+			default:
+				out.WriteString("<td>" + html.EscapeString(truncate(s, opts.MaxColumnWidth, "…")) + "</td>")
+			}
+		}
+		out.WriteString("</tr>\n")
+	}
+	out.WriteString("</tbody>\n</table>\n")
+	return out.Flush()
+}
+
+func writeCSV(w io.Writer, t Table, opts Options) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(t.Columns); err != nil {
+		return err
+	}
+	for _, r := range t.Rows {
+		record := make([]string, len(t.Columns))
+		for i := range t.Columns {
+			var v interface{}
+			if i < len(r) {
+				v = r[i]
+			}
+			s, ok := text(v)
+			if !ok {
+				s = opts.Null
+			}
+			record[i] = s
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeJSON writes an array of objects, keeping each object's keys in column order.
+func writeJSON(w io.Writer, t Table) error {
+	out := bufio.NewWriter(w)
+	out.WriteString("[")
+	for n, r := range t.Rows {
+		if n > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n  {")
+		for i, c := range t.Columns {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			var v interface{}
+			if i < len(r) {
+				v = r[i]
+			}
+			key, _ := json.Marshal(c)
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			out.Write(key)
+			out.WriteString(": ")
+			out.Write(value)
+		}
+		out.WriteString("}")
+	}
+	if len(t.Rows) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("]\n")
+	return out.Flush()
+}
+
+// writeYAML writes a sequence of mappings, keeping each mapping's keys in column order.
+// yaml.v2 quotes any string that would read back as something else, such as a date, yes
+// or 12. Rows are marshaled one at a time, so the table isn't encoded all at once.
+func writeYAML(w io.Writer, t Table) error {
+	out := bufio.NewWriter(w)
+	if len(t.Rows) == 0 {
+		out.WriteString("[]\n")
+	}
+	for _, r := range t.Rows {
+		row := make(yaml.MapSlice, len(t.Columns))
+		for i, c := range t.Columns {
+			var v interface{}
+			if i < len(r) {
+				v = r[i]
+			}
+			row[i] = yaml.MapItem{Key: c, Value: yamlValue(v)}
+		}
+		b, err := yaml.Marshal([]yaml.MapSlice{row})
+		if err != nil {
+			return err
+		}
+		out.Write(b)
+	}
+	return out.Flush()
+}
+
+// yamlValue leaves the values YAML has its own form for, and writes the rest as text.
+func yamlValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, bool, string, time.Time:
+		return v
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+	}
+	if isNumber(v) {
+		return v
+	}
+	s, _ := text(v)
+	return s
+}
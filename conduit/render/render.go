@@ -0,0 +1,216 @@
+// Package render writes Conduit metadata and query results as tables, Markdown, HTML,
+// CSV, JSON or YAML.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	conduit "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	ASCII    Format = "ascii"
+	Unicode  Format = "unicode"
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	CSV      Format = "csv"
+	JSON     Format = "json"
+	YAML     Format = "yaml"
+)
+
+// Formats lists every format, in the order they're usually offered.
+var Formats = []Format{ASCII, Unicode, Markdown, HTML, CSV, JSON, YAML}
+
+// ParseFormat reads a format name, as given to a --format flag.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown format %q, expected one of %v", s, strings.Join(names, ", "))
+}
+
+// Options control how a Table is written. The zero value writes an ASCII table.
+type Options struct {
+	Format Format
+	// MaxWidth is the widest an ASCII or Unicode table may be; the widest columns are cut
+	// down to fit. Zero uses the terminal's width when writing to one, and no limit otherwise.
+	MaxWidth int
+	// MaxColumnWidth truncates longer values in tables, Markdown and HTML. Zero means no limit.
+	MaxColumnWidth int
+	// Null is shown for missing values in every format but JSON and YAML. It defaults to
+	// "NULL", or to nothing for CSV.
+	Null string
+}
+
+// Table is rows of values under named columns.
+type Table struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Render writes t to w in opts.Format.
+func Render(w io.Writer, t Table, opts Options) error {
+	switch opts.Format {
+	case "", ASCII:
+		return writeGrid(w, t, opts, asciiBox)
+	case Unicode:
+		return writeGrid(w, t, opts, unicodeBox)
+	case Markdown:
+		return writeMarkdown(w, t, opts)
+	case HTML:
+		return writeHTML(w, t, opts)
+	case CSV:
+		return writeCSV(w, t, opts)
+	case JSON:
+		return writeJSON(w, t)
+	case YAML:
+		return writeYAML(w, t)
+	}
+	return fmt.Errorf("unknown format %q", opts.Format)
+}
+
+// Databases is a one-column table of database names.
+func Databases(d *conduit.DatabasesStruct) Table {
+	t := Table{Columns: []string{"database"}}
+	for _, name := range d.Databases {
+		t.Rows = append(t.Rows, []interface{}{name})
+	}
+	return t
+}
+
+// Tables lists tables with their database, schema and type.
+func Tables(ts *conduit.TablesStruct) Table {
+	t := Table{Columns: []string{"table", "database", "schema", "type"}}
+	for _, v := range ts.Tables {
+		t.Rows = append(t.Rows, []interface{}{v.Table, v.Database, v.Schema, v.TableType})
+	}
+	return t
+}
+
+// Columns lists column definitions, one per row.
+func Columns(cols []conduit.ColumnStruct) Table {
+	t := Table{Columns: []string{"name", "type", "length", "scale", "sqlType"}}
+	for _, c := range cols {
+		t.Rows = append(t.Rows, []interface{}{c.Name, c.ColType, optValue(c.LengthOpt), optValue(c.ScaleOpt), conduit.SqlType(c.SqlType).String()})
+	}
+	return t
+}
+
+// Schema lists a table's columns.
+func Schema(s *conduit.TableSchemaStruct) Table {
+	return Columns(s.Columns)
+}
+
+// Results joins the rows of every page, in the columns of the first page that names them.
+func Results(pages ...conduit.QueryResultStruct) Table {
+	var columns []string
+	var rows []map[string]interface{}
+	for _, p := range pages {
+		if columns == nil && len(p.ParsedColumns) > 0 {
+			columns = p.ParsedColumns
+		}
+		rows = append(rows, p.ParsedRows...)
+	}
+	return Rows(columns, rows)
+}
+
+// Rows makes a table of rows keyed by column name. With no columns given, they are the
+// rows' keys in sorted order.
+func Rows(columns []string, rows []map[string]interface{}) Table {
+	if len(columns) == 0 {
+		seen := map[string]bool{}
+		for _, r := range rows {
+			for k := range r {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+	t := Table{Columns: columns}
+	for _, r := range rows {
+		row := make([]interface{}, len(columns))
+		for i, c := range columns {
+			row[i] = r[c]
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+func optValue(opt *int) interface{} {
+	if opt == nil {
+		return nil
+	}
+	return *opt
+}
+
+// text formats a value for the text formats; ok is false for nil.
+func text(v interface{}) (s string, ok bool) {
+	switch t := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return t, true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32), true
+	case time.Time:
+		return t.Format(time.RFC3339Nano), true
+	case []byte:
+		return string(t), true
+	case fmt.Stringer:
+		return t.String(), true
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t), true
+		}
+		return string(b), true
+	}
+	return fmt.Sprint(v), true
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return true
+	}
+	return false
+}
+
+// truncate shortens s to width runes, ending it with ellipsis.
+func truncate(s string, width int, ellipsis string) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	e := utf8.RuneCountInString(ellipsis)
+	if width <= e {
+		return string([]rune(s)[:width])
+	}
+	return strings.TrimRight(string([]rune(s)[:width-e]), " ") + ellipsis
+}
+
+// oneLine keeps a value on one line of a table.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(s)
+}
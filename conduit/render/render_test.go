@@ -0,0 +1,160 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	conduit "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+	"gopkg.in/yaml.v2"
+)
+
+var sample = Table{
+	Columns: []string{"name", "count", "note"},
+	Rows: [][]interface{}{
+		{"alpha", float64(12), nil},
+		{"beta", float64(3.5), "a | b"},
+	},
+}
+
+func renderString(t *testing.T, table Table, opts Options) string {
+	var b bytes.Buffer
+	if err := Render(&b, table, opts); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{ASCII, `+-------+-------+-------+
+| name  | count | note  |
++-------+-------+-------+
+| alpha |    12 | NULL  |
+| beta  |   3.5 | a | b |
++-------+-------+-------+
+`},
+		{Unicode, `┌───────┬───────┬───────┐
+│ name  │ count │ note  │
+├───────┼───────┼───────┤
+│ alpha │    12 │ NULL  │
+│ beta  │   3.5 │ a | b │
+└───────┴───────┴───────┘
+`},
+		{Markdown, `| name | count | note |
+| --- | ---: | --- |
+| alpha | 12 | NULL |
+| beta | 3.5 | a \| b |
+`},
+		{HTML, `<table>
+<thead>
+<tr><th>name</th><th>count</th><th>note</th></tr>
+</thead>
+<tbody>
+<tr><td>alpha</td><td class="number">12</td><td class="null">NULL</td></tr>
+<tr><td>beta</td><td class="number">3.5</td><td>a | b</td></tr>
+</tbody>
+</table>
+`},
+		{CSV, `name,count,note
+alpha,12,
+beta,3.5,a | b
+`},
+		{JSON, `[
+  {"name": "alpha", "count": 12, "note": null},
+  {"name": "beta", "count": 3.5, "note": "a | b"}
+]
+`},
+		{YAML, `- name: alpha
+  count: 12
+  note: null
+- name: beta
+  count: 3.5
+  note: a | b
+`},
+	}
+	for _, test := range tests {
+		if actual := renderString(t, sample, Options{Format: test.format}); actual != test.expected {
+			t.Errorf("%v\nActual:\n%v\n=====\nExpected:\n%v", test.format, actual, test.expected)
+		}
+	}
+}
+
+func TestRender_YAMLStrings(t *testing.T) {
+	values := []string{"2020-01-02", "2020-01-02T03:04:05Z", "yes", "No", "on", "y", "12", "0x1F", "1e3", ".5", "null", "~", "", " padded", "a: b", "- item", "#note", "two\nlines"}
+	table := Table{Columns: []string{"value"}}
+	for _, v := range values {
+		table.Rows = append(table.Rows, []interface{}{v})
+	}
+	var decoded []map[string]interface{}
+	if err := yaml.Unmarshal([]byte(renderString(t, table, Options{Format: YAML})), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		if actual := decoded[i]["value"]; actual != v {
+			t.Errorf("Actual: %#v\n=====\nExpected: %q read back as a string", actual, v)
+		}
+	}
+}
+
+func TestRender_Widths(t *testing.T) {
+	table := Table{Columns: []string{"id", "description"}, Rows: [][]interface{}{{"1", "a rather long description\nover two lines"}}}
+
+	actual := renderString(t, table, Options{Format: Unicode, MaxColumnWidth: 10})
+	if !strings.Contains(actual, "│ a rather…  │") {
+		t.Errorf("Expected the description cut to 10 characters:\n%v", actual)
+	}
+	actual = renderString(t, table, Options{MaxWidth: 30, Null: "-"})
+	for _, line := range strings.Split(strings.TrimSpace(actual), "\n") {
+		if len(line) != 30 {
+			t.Errorf("Actual: %v wide\n=====\nExpected: 30\n%v", len(line), actual)
+		}
+	}
+	if !strings.Contains(actual, "| a rather long desc... |") {
+		t.Errorf("Expected the widest column to shrink to fit:\n%v", actual)
+	}
+}
+
+func TestResults(t *testing.T) {
+	pages := []conduit.QueryResultStruct{
+		{ParsedColumns: []string{"b", "a"}, ParsedRows: []map[string]interface{}{{"a": 1, "b": 2}}},
+		{ParsedRows: []map[string]interface{}{{"a": 3, "b": nil}}},
+	}
+	actual := renderString(t, Results(pages...), Options{Format: CSV, Null: "NULL"})
+	if expected := "b,a\n2,1\nNULL,3\n"; actual != expected {
+		t.Errorf("Actual: %q\n=====\nExpected: %q", actual, expected)
+	}
+	if actual := Rows(nil, pages[0].ParsedRows).Columns; strings.Join(actual, ",") != "a,b" {
+		t.Errorf("Actual: %v\n=====\nExpected: [a b]", actual)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	length := 10
+	schema := Schema(&conduit.TableSchemaStruct{Columns: []conduit.ColumnStruct{
+		{Name: "ID", ColType: "NUMBER", LengthOpt: &length, SqlType: int(conduit.SqlTypeInteger)},
+	}})
+	actual := renderString(t, schema, Options{Format: YAML})
+	if expected := "- name: ID\n  type: NUMBER\n  length: 10\n  scale: null\n  sqlType: INTEGER\n"; actual != expected {
+		t.Errorf("Actual:\n%v\n=====\nExpected:\n%v", actual, expected)
+	}
+	tables := Tables(&conduit.TablesStruct{Tables: []conduit.TableStruct{{Table: "T", Database: "D", Schema: "S", TableType: "TABLE"}}})
+	if len(tables.Rows) != 1 || tables.Rows[0][0] != "T" {
+		t.Errorf("Unexpected tables: %+v", tables)
+	}
+	if dbs := Databases(&conduit.DatabasesStruct{Databases: []string{"x", "y"}}); len(dbs.Rows) != 2 {
+		t.Errorf("Unexpected databases: %+v", dbs)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("Markdown"); f != Markdown || err != nil {
+		t.Errorf("Actual: %v (%v)\n=====\nExpected: %v", f, err, Markdown)
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Errorf("Expected pdf to be refused")
+	}
+}
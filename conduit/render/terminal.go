@@ -0,0 +1,37 @@
+package render
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// TerminalWidth is the width of the terminal w writes to, or 0 when w isn't a terminal or
+// its width can't be found. $COLUMNS wins when it's set, then `stty size` is asked.
+func TerminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return 0
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) != 2 {
+		return 0
+	}
+	n, _ := strconv.Atoi(fields[1])
+	return n
+}
@@ -0,0 +1,175 @@
+package conduit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errRowsClosed stops a query whose Rows were closed before every row was read.
+var errRowsClosed = errors.New("rows closed")
+
+// Rows iterates over a query's results as they stream in. Each row is handed over as soon
+// as it is decoded, and the next page is only fetched once the rows before it have been
+// read, so no page is held in memory whole. Stopping early, through Close or
+// QueryOptions.MaxRows, cancels the query on Conduit instead of letting it run to completion.
+// Close must be called once done with the rows.
+//
+//	rows, err := client.QueryRows(ctx, "SELECT * FROM `db`.`t`")
+//	...
+//	defer rows.Close()
+//	for rows.Next() {
+//		row := rows.Row()
+//	}
+//	err = rows.Err()
+type Rows struct {
+	client  *ConduitClient
+	query   QueryStruct // belongs to the goroutine reading the query until rows is closed
+	maxRows int
+	rows    chan map[string]interface{}
+	runErr  error // why the query stopped, set before rows is closed
+	sent    bool  // the goroutine has handed over a row
+	closing chan struct{}
+	stop    context.CancelFunc
+
+	columns []string
+	next    map[string]interface{} // the first row, read before QueryRows returns
+	row     map[string]interface{}
+	read    int
+	done    bool
+	err     error
+}
+
+// QueryRows starts sqlString with the client's Options and returns an iterator over its rows.
+// Args are bound to placeholders as in ExecuteQuery.
+func (c *ConduitClient) QueryRows(ctx context.Context, sqlString string, args ...interface{}) (*Rows, error) {
+	return c.QueryRowsWithOptions(ctx, c.Options, sqlString, args...)
+}
+
+// QueryRowsWithOptions is QueryRows with opts in place of the client's Options. It waits
+// for the first row, or the end of the query, before it returns, so a query that fails
+// to start gives an error here.
+func (c *ConduitClient) QueryRowsWithOptions(ctx context.Context, opts QueryOptions, sqlString string, args ...interface{}) (*Rows, error) {
+	boundSql, err := Bind(sqlString, args...)
+	if err != nil {
+		return nil, err
+	}
+	q, err := NewQueryWithOptions(boundSql, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Rows counts MaxRows itself, since it keeps no pages to trim.
+	q.Options.MaxRows = 0
+	ctx, stop := context.WithCancel(ctx)
+	r := &Rows{client: c, query: q, maxRows: opts.MaxRows, rows: make(chan map[string]interface{}),
+		closing: make(chan struct{}), stop: stop}
+	r.query.onRow = func(columns []string, row map[string]interface{}) error {
+		return r.send(ctx, columns, row)
+	}
+	go r.run(ctx)
+	row, ok := <-r.rows
+	if !ok && r.runErr != nil {
+		err := r.runErr
+		r.Close()
+		return nil, err
+	}
+	r.next = row
+	return r, nil
+}
+
+// run reads the query, handing its rows to Next through r.rows.
+func (r *Rows) run(ctx context.Context) {
+	defer close(r.rows)
+	r.runErr = r.client.run(ctx, &r.query)
+	if !r.sent && len(r.query.QueryResults) > 0 {
+		r.columns = r.query.QueryResults[0].ParsedColumns
+	}
+}
+
+// send waits for Next to take row. Time spent waiting isn't time the query ran on
+// Conduit, so it doesn't count toward the Timeout.
+func (r *Rows) send(ctx context.Context, columns []string, row map[string]interface{}) error {
+	if !r.sent {
+		r.columns = columns
+		r.sent = true
+	}
+	waiting := time.Now()
+	defer func() { r.query.StartTime = r.query.StartTime.Add(time.Since(waiting)) }()
+	select {
+	case r.rows <- row:
+		return nil
+	case <-r.closing:
+		return errRowsClosed
+	case <-ctx.Done():
+		select {
+		case <-r.closing:
+			return errRowsClosed
+		default:
+		}
+		return ctx.Err()
+	}
+}
+
+// Next moves to the next row, fetching another page when needed. It returns false once
+// the rows run out, MaxRows is reached, or an error occurs; check Err afterwards.
+func (r *Rows) Next() bool {
+	if r.done {
+		return false
+	}
+	if r.maxRows > 0 && r.read >= r.maxRows {
+		r.Close()
+		return false
+	}
+	row := r.next
+	r.next = nil
+	if row == nil {
+		var ok bool
+		if row, ok = <-r.rows; !ok {
+			r.err = r.runErr
+			r.Close()
+			return false
+		}
+	}
+	r.row = row
+	r.read++
+	return true
+}
+
+// Row is the current row, keyed by column name.
+func (r *Rows) Row() map[string]interface{} {
+	return r.row
+}
+
+// Columns are the result's column names, in order.
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Err is the error that stopped Next, if any.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close stops the iteration. If the query hadn't run to its end it is cancelled on Conduit.
+// It is safe to call more than once.
+func (r *Rows) Close() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	r.row, r.next = nil, nil
+	close(r.closing)
+	r.stop()
+	for range r.rows {
+		// Wait for the query to stop.
+	}
+	if r.runErr == nil {
+		return nil
+	}
+	result, err := r.client.cancelDetached(&r.query)
+	if err != nil {
+		return fmt.Errorf("cancelling QueryId %v: %v", result.QueryId, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package conduit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+// registerPages serves three pages of two rows each for query q1, one per POST.
+func registerPages(server string) {
+	pages := []string{
+		`{"queryId":"q1","status":"ResultsReady","message":null,"data":{"columns":["a"],"rows":[{"a":1},{"a":2}],"hasNext":true,"hasPrevious":false}}`,
+		`{"queryId":"q1","status":"ResultsReady","message":null,"data":{"columns":["a"],"rows":[{"a":3},{"a":4}],"hasNext":true,"hasPrevious":true}}`,
+		`{"queryId":"q1","status":"Finished","message":null,"data":{"columns":["a"],"rows":[{"a":5},{"a":6}],"hasNext":false,"hasPrevious":true}}`,
+	}
+	posts := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://%v/api/query/execute", server),
+		func(req *http.Request) (*http.Response, error) {
+			posts++
+			return httpmock.NewStringResponse(200, pages[posts-1]), nil
+		})
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://%v/api/query/cancel?queryId=q1", server),
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+}
+
+func TestRows_All(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	registerPages(server)
+
+	rows, err := NewClient(server, viper.GetString("CONDUIT_TOKEN")).QueryRows(context.Background(), "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("QueryRows failed: %v", err)
+	}
+	defer rows.Close()
+	var got []interface{}
+	for rows.Next() {
+		got = append(got, rows.Row()["a"])
+	}
+	if rows.Err() != nil {
+		t.Fatalf("Err: %v", rows.Err())
+	}
+	if fmt.Sprint(got) != "[1 2 3 4 5 6]" || fmt.Sprint(rows.Columns()) != "[a]" {
+		t.Errorf("Actual: %v %v\n=====\nExpected: [1 2 3 4 5 6] [a]", got, rows.Columns())
+	}
+	if calls := httpmock.GetCallCountInfo()[fmt.Sprintf("GET https://%v/api/query/cancel?queryId=q1", server)]; calls != 0 {
+		t.Errorf("A finished query was cancelled %v times", calls)
+	}
+	for _, qrs := range rows.query.QueryResults {
+		if len(qrs.ParsedRows) != 0 {
+			t.Errorf("Actual: %v rows kept in a page\n=====\nExpected: rows handed over as they're decoded, not kept", len(qrs.ParsedRows))
+		}
+	}
+}
+
+func TestRows_MaxRows(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	registerPages(server)
+
+	rows, err := NewClient(server, viper.GetString("CONDUIT_TOKEN")).QueryRowsWithOptions(context.Background(),
+		QueryOptions{PageSize: 2, MaxRows: 3}, "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("QueryRows failed: %v", err)
+	}
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	if n != 3 || rows.Err() != nil {
+		t.Errorf("Actual: %v rows (%v)\n=====\nExpected: 3 rows", n, rows.Err())
+	}
+	info := httpmock.GetCallCountInfo()
+	if posts := info[fmt.Sprintf("POST https://%v/api/query/execute", server)]; posts != 2 {
+		t.Errorf("Actual: %v pages fetched\n=====\nExpected: 2", posts)
+	}
+	if calls := info[fmt.Sprintf("GET https://%v/api/query/cancel?queryId=q1", server)]; calls != 1 {
+		t.Errorf("Actual: %v cancels\n=====\nExpected: 1", calls)
+	}
+}
+
+func TestRows_CloseEarly(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	server := viper.GetString("CONDUIT_SERVER")
+	registerPages(server)
+
+	c := NewClient(server, viper.GetString("CONDUIT_TOKEN"))
+	rows, err := c.QueryRows(context.Background(), "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("QueryRows failed: %v", err)
+	}
+	if !rows.Next() {
+		t.Fatalf("Expected a first row: %v", rows.Err())
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if rows.Next() {
+		t.Errorf("Next returned a row after Close")
+	}
+	rows.Close()
+	info := httpmock.GetCallCountInfo()
+	if calls := info[fmt.Sprintf("GET https://%v/api/query/cancel?queryId=q1", server)]; calls != 1 {
+		t.Errorf("Actual: %v cancels\n=====\nExpected: 1", calls)
+	}
+	if ids := c.ActiveQueryIds(); len(ids) != 0 {
+		t.Errorf("Query still tracked after Close: %v", ids)
+	}
+}
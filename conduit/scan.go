@@ -0,0 +1,295 @@
+package conduit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are tried in order when a string value is scanned into a time.Time field.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// Scan decodes every row fetched for the query into dst, which must be a pointer
+// to a slice of structs. Fields are matched on their `conduit` tag, or their name.
+// Integers past 2^53, which ParsedRows holds as float64s, are scanned with every digit.
+func (q *QueryStruct) Scan(dst interface{}) error {
+	var rows []map[string]interface{}
+	for _, v := range q.QueryResults {
+		for i := range v.ParsedRows {
+			rows = append(rows, v.exactRow(i))
+		}
+	}
+	return ScanRows(rows, dst)
+}
+
+// ScanRows decodes parsed rows into dst, which must be a pointer to a slice of structs.
+// Numbers may be float64s, as in ParsedRows, or json.Numbers, which are scanned exactly.
+func ScanRows(rows []map[string]interface{}, dst interface{}) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ScanRows needs a pointer to a slice, got %T", dst)
+	}
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("ScanRows needs a slice of structs, got %v", slice.Type())
+	}
+	fields := scanFields(elemType)
+	for i, row := range rows {
+		elem := reflect.New(elemType).Elem()
+		for name, idx := range fields {
+			value, ok := row[name]
+			if !ok {
+				continue
+			}
+			if err := assignValue(elem.Field(idx), value); err != nil {
+				return fmt.Errorf("row %v, column %v: %v", i, name, err)
+			}
+		}
+		if isPtr {
+			slice = reflect.Append(slice, elem.Addr())
+		} else {
+			slice = reflect.Append(slice, elem)
+		}
+	}
+	ptr.Elem().Set(slice)
+	return nil
+}
+
+// scanFields maps column names to struct field indexes.
+func scanFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("conduit"); ok {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+func assignValue(field reflect.Value, value interface{}) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		target := reflect.New(field.Type().Elem())
+		if err := assignValue(target.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(target)
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into time.Time", value)
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot parse %q as a time", s)
+	}
+	switch field.Kind() {
+	case reflect.Interface:
+		field.Set(v)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := intValue(value)
+		if !ok {
+			return fmt.Errorf("cannot scan %v (%T) into %v", value, value, field.Type())
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("%v overflows %v", value, field.Type())
+		}
+		field.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := uintValue(value)
+		if !ok {
+			return fmt.Errorf("cannot scan %v (%T) into %v", value, value, field.Type())
+		}
+		if field.OverflowUint(n) {
+			return fmt.Errorf("%v overflows %v", value, field.Type())
+		}
+		field.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := floatValue(value)
+		if !ok {
+			return fmt.Errorf("cannot scan %T into %v", value, field.Type())
+		}
+		field.SetFloat(f)
+		return nil
+	case reflect.String:
+		if n, ok := value.(json.Number); ok {
+			field.SetString(n.String())
+			return nil
+		}
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := value.(string); ok {
+				field.SetBytes([]byte(s))
+				return nil
+			}
+		}
+	}
+	if v.Type().ConvertibleTo(field.Type()) && v.Kind() == field.Kind() {
+		field.Set(v.Convert(field.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into %v", value, field.Type())
+}
+
+// intValue reads a whole number from a float64, as ParsedRows holds them, from a
+// json.Number, as Scan passes integers too large for a float64, or from an int.
+func intValue(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := strconv.ParseInt(v.String(), 10, 64)
+		return n, err == nil
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+func uintValue(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := strconv.ParseUint(v.String(), 10, 64)
+		return n, err == nil
+	case float64:
+		return uint64(v), v >= 0 && v == float64(uint64(v))
+	case int:
+		return uint64(v), v >= 0
+	case int64:
+		return uint64(v), v >= 0
+	}
+	return 0, false
+}
+
+func floatValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// maxExactInt is the largest integer a float64 holds exactly.
+const maxExactInt = 1 << 53
+
+// exactNumbers holds the text of the integers in one row that a float64 can't hold.
+type exactNumbers map[string]json.Number
+
+// addRow appends row to ParsedRows with its numbers as float64s, keeping aside the
+// integers that lose digits that way so Scan can still fill int64 fields exactly.
+func (r *QueryResultStruct) addRow(row map[string]interface{}) {
+	if exact := plainNumbers(row); exact != nil {
+		if r.exact == nil {
+			r.exact = map[int]exactNumbers{}
+		}
+		r.exact[len(r.ParsedRows)] = exact
+	}
+	r.ParsedRows = append(r.ParsedRows, row)
+}
+
+// exactRow is ParsedRows[i] with the integers that lost digits put back as json.Numbers.
+func (r QueryResultStruct) exactRow(i int) map[string]interface{} {
+	row, exact := r.ParsedRows[i], r.exact[i]
+	if exact == nil {
+		return row
+	}
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	for k, n := range exact {
+		out[k] = n
+	}
+	return out
+}
+
+// plainNumbers turns the json.Numbers in a row decoded with UseNumber into float64s,
+// and returns the top-level integers that lost digits on the way.
+func plainNumbers(row map[string]interface{}) exactNumbers {
+	var exact exactNumbers
+	for k, v := range row {
+		if n, ok := v.(json.Number); ok && !fitsFloat(n) {
+			if exact == nil {
+				exact = exactNumbers{}
+			}
+			exact[k] = n
+		}
+		row[k] = floatNumbers(v)
+	}
+	return exact
+}
+
+func floatNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case []interface{}:
+		for i, e := range t {
+			t[i] = floatNumbers(e)
+		}
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = floatNumbers(e)
+		}
+	}
+	return v
+}
+
+// fitsFloat reports whether n is a decimal, or an integer a float64 holds exactly.
+func fitsFloat(n json.Number) bool {
+	s := n.String()
+	if strings.ContainsAny(s, ".eE") {
+		return true
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	return err == nil && i >= -maxExactInt && i <= maxExactInt
+}
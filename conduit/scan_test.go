@@ -0,0 +1,75 @@
+package conduit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryStruct_Scan(t *testing.T) {
+	type passenger struct {
+		Id    int64     `conduit:"PassengerId"`
+		Name  string    `conduit:"Name"`
+		Fare  float64   `conduit:"Fare"`
+		Cabin *string   `conduit:"Cabin"`
+		Seen  time.Time `conduit:"Seen"`
+		Skip  string    `conduit:"-"`
+	}
+	qrs, err := UnmarshalJsonToQueryResult(`{"queryId":"1","status":"Finished","data":{"columns":["PassengerId","Name","Fare","Cabin","Seen"],"rows":[{"PassengerId":1,"Name":"Braund, Mr. Owen Harris","Fare":7.25,"Cabin":null,"Seen":"2020-01-02"},{"PassengerId":2,"Name":"Cumings, Mrs. John Bradley","Fare":71.2833,"Cabin":"C85","Seen":"2020-01-03T10:00:00Z"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := QueryStruct{QueryResults: []QueryResultStruct{qrs}}
+	var rows []passenger
+	if err := q.Scan(&rows); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Actual: %v rows, Expected: 2", len(rows))
+	}
+	if rows[0].Id != 1 || rows[0].Cabin != nil || rows[0].Seen.Day() != 2 {
+		t.Errorf("First row scanned wrong: %+v", rows[0])
+	}
+	if rows[1].Cabin == nil || *rows[1].Cabin != "C85" || rows[1].Fare != 71.2833 {
+		t.Errorf("Second row scanned wrong: %+v", rows[1])
+	}
+}
+
+func TestScanRowsRejectsBadTypes(t *testing.T) {
+	type row struct {
+		Count int `conduit:"count"`
+	}
+	var rows []row
+	err := ScanRows([]map[string]interface{}{{"count": "many"}}, &rows)
+	if err == nil {
+		t.Errorf("Scanning a string into an int should fail")
+	}
+	if err := ScanRows(nil, rows); err == nil {
+		t.Errorf("Scanning into a non-pointer should fail")
+	}
+}
+
+func TestScanRows_BigInt(t *testing.T) {
+	type row struct {
+		Id    int64  `conduit:"id"`
+		Count uint64 `conduit:"count"`
+	}
+	// Neither fits in a float64 without losing digits.
+	qrs, err := UnmarshalJsonToQueryResult(`{"queryId":"1","status":"Finished","data":{"columns":["id","count"],"rows":[{"id":9007199254740993,"count":18446744073709551615}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := qrs.ParsedRows[0]["id"].(float64); !ok {
+		t.Errorf("Actual: %T\n=====\nExpected: ParsedRows to keep float64 numbers", qrs.ParsedRows[0]["id"])
+	}
+	q := QueryStruct{QueryResults: []QueryResultStruct{qrs}}
+	var rows []row
+	if err := q.Scan(&rows); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if rows[0].Id != 9007199254740993 || rows[0].Count != 18446744073709551615 {
+		t.Errorf("Actual: %+v\n=====\nExpected: {Id:9007199254740993 Count:18446744073709551615}", rows[0])
+	}
+	if err := ScanRows([]map[string]interface{}{{"id": 1.5}}, &rows); err == nil {
+		t.Errorf("Scanning 1.5 into an int64 should fail")
+	}
+}
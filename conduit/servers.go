@@ -0,0 +1,237 @@
+package conduit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ServerDownFor is how long a server that couldn't be reached is passed over, unless
+// every server is down or a health check finds it back up first.
+var ServerDownFor = 30 * time.Second
+
+// ServerStatus is what the client knows about one Conduit server.
+type ServerStatus struct {
+	Server    string
+	Healthy   bool
+	LastError error     // why it was marked down
+	DownUntil time.Time // when it will be tried again without a health check
+}
+
+type serverState struct {
+	lastErr   error
+	downUntil time.Time
+}
+
+// servers lists ConduitServer followed by Servers, without blanks or repeats.
+func (c *ConduitClient) servers() []string {
+	seen := map[string]bool{}
+	var all []string
+	for _, s := range append([]string{c.ConduitServer}, c.Servers...) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			all = append(all, s)
+		}
+	}
+	return all
+}
+
+// candidates orders the servers for a request that may go to any of them: healthy ones
+// first, rotated so load spreads across them, then the rest in case they have recovered.
+func (c *ConduitClient) candidates() []string {
+	all := c.servers()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	var healthy, down []string
+	for _, s := range all {
+		if st, ok := c.health[s]; ok && now.Before(st.downUntil) {
+			down = append(down, s)
+		} else {
+			healthy = append(healthy, s)
+		}
+	}
+	if n := len(healthy); n > 1 {
+		start := c.nextServer % n
+		c.nextServer++
+		healthy = append(healthy[start:], healthy[:start]...)
+	}
+	return append(healthy, down...)
+}
+
+func (c *ConduitClient) markServer(server string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		delete(c.health, server)
+		return
+	}
+	if c.health == nil {
+		c.health = map[string]serverState{}
+	}
+	c.health[server] = serverState{lastErr: err, downUntil: time.Now().Add(ServerDownFor)}
+}
+
+// ServerStatuses reports every configured server, in configuration order.
+func (c *ConduitClient) ServerStatuses() []ServerStatus {
+	all := c.servers()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var statuses []ServerStatus
+	for _, s := range all {
+		st := c.health[s]
+		statuses = append(statuses, ServerStatus{
+			Server:    s,
+			Healthy:   !time.Now().Before(st.downUntil),
+			LastError: st.lastErr,
+			DownUntil: st.downUntil,
+		})
+	}
+	return statuses
+}
+
+// CheckServers probes every server by listing databases, marks each up or down, and
+// returns the statuses.
+func (c *ConduitClient) CheckServers(ctx context.Context) []ServerStatus {
+	for _, s := range c.servers() {
+		resp, err := c.do(ctx, s, "GET", "/metadata/databases", nil)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				c.markServer(s, fmt.Errorf("Status Code %v returned", resp.StatusCode))
+			}
+		}
+	}
+	return c.ServerStatuses()
+}
+
+// WatchServers runs CheckServers every interval until ctx ends.
+func (c *ConduitClient) WatchServers(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.CheckServers(ctx)
+		}
+	}
+}
+
+// do sends one request to server through the middleware chain. Every call to Conduit goes
+// through here. It waits its turn under RequestsPerSecond, and retries after a 429 once
+// Retry-After has passed. A failure to connect to the server marks it down; any response marks
+// it up. Errors after the request may have been sent, such as a read timeout, leave it as it was.
+func (c *ConduitClient) do(ctx context.Context, server, method, endpoint string, body []byte) (*http.Response, error) {
+	limiter := c.bucket()
+	for attempt := 1; ; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		req, err := c.newRequest(ctx, server, method, endpoint, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.roundTrip(server, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			limiter.succeeded()
+			return resp, nil
+		}
+		wait := retryAfter(resp)
+		limiter.throttled(wait)
+		if attempt > MaxThrottleRetries {
+			// Hand the 429 back to the caller, which reports the status code.
+			return resp, nil
+		}
+		resp.Body.Close()
+		log.Printf("Conduit server %v is throttling requests, waiting %v", server, wait)
+	}
+}
+
+// newRequest builds a request for endpoint on server, with the token and headers every call carries.
+func (c *ConduitClient) newRequest(ctx context.Context, server, method, endpoint string, body []byte) (*http.Request, error) {
+	formedUrl := fmt.Sprintf("https://%v/api%v", server, endpoint)
+	req, err := http.NewRequestWithContext(ctx, method, formedUrl, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error forming URL: %s", err.Error())
+		return nil, err
+	}
+	req.Header.Set("accept", "application/json")
+	token := c.ConduitToken
+	if c.TokenSource != nil {
+		if token, err = c.TokenSource.Token(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(c.Compression) > 0 {
+		accept, err := c.acceptEncoding()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept-Encoding", accept)
+	}
+	return req, nil
+}
+
+// roundTrip sends req once through the middleware chain, marking server up or down by how it went.
+func (c *ConduitClient) roundTrip(server string, req *http.Request) (*http.Response, error) {
+	resp, err := c.roundTripper()(req)
+	if err != nil {
+		log.Printf("Error doing request: %s", err.Error())
+		if req.Context().Err() == nil && unreachable(err) {
+			c.markServer(server, err)
+		}
+		return nil, err
+	}
+	c.markServer(server, nil)
+	return resp, nil
+}
+
+// unreachable reports whether err means the request never got to the server: it couldn't
+// be resolved or connected to.
+func unreachable(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// doAny sends the request to the first server that can be connected to, and says which one
+// it was. It only moves on to the next server when the request can't have been sent.
+func (c *ConduitClient) doAny(ctx context.Context, method, endpoint string, body []byte) (*http.Response, string, error) {
+	var lastErr error
+	for _, s := range c.candidates() {
+		resp, err := c.do(ctx, s, method, endpoint, body)
+		if err == nil {
+			return resp, s, nil
+		}
+		if ctx.Err() != nil || !unreachable(err) {
+			// The request may have reached the server, which could be running it: sending
+			// it to another would run it twice.
+			return nil, "", err
+		}
+		lastErr = err
+		log.Printf("Conduit server %v can't be reached, trying the next one", s)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no Conduit server configured")
+	}
+	return nil, "", lastErr
+}
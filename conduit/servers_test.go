@@ -0,0 +1,108 @@
+package conduit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestConduitClient_Failover(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("POST", "https://down.example/api/query/execute",
+		httpmock.NewErrorResponder(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}))
+	httpmock.RegisterResponder("POST", "https://up.example/api/query/execute",
+		httpmock.NewStringResponder(200, `{"queryId":"q1","status":"Running","message":null,"data":null}`))
+	httpmock.RegisterResponder("GET", "https://up.example/api/query/execute/q1/result",
+		httpmock.NewStringResponder(200, `{"queryId":"q1","status":"Finished","message":null,"data":{"columns":["a"],"rows":[{"a":1}],"hasNext":false,"hasPrevious":false}}`))
+
+	c := NewClient("down.example", viper.GetString("CONDUIT_TOKEN"))
+	c.Servers = []string{"up.example"}
+	c.Options.PollStrategy = ConstantPoll(time.Millisecond)
+	err := c.ExecuteQuery(context.Background(), "SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("Execute didn't fail over: %v", err)
+	}
+	if c.Query.Server != "up.example" || len(c.Query.QueryResults) != 1 {
+		t.Errorf("Actual: %v with %v pages\n=====\nExpected: up.example with 1 page", c.Query.Server, len(c.Query.QueryResults))
+	}
+	statuses := c.ServerStatuses()
+	if len(statuses) != 2 || statuses[0].Healthy || statuses[0].LastError == nil || !statuses[1].Healthy {
+		t.Errorf("Unexpected statuses: %+v", statuses)
+	}
+
+	// The down server is passed over for new queries until it has been down ServerDownFor.
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := httpmock.GetCallCountInfo()["POST https://down.example/api/query/execute"]; calls != 1 {
+		t.Errorf("Actual: %v calls to the down server\n=====\nExpected: 1", calls)
+	}
+}
+
+func TestConduitClient_NoFailoverAfterSending(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	// The first server took the query but its answer never came back.
+	httpmock.RegisterResponder("POST", "https://slow.example/api/query/execute",
+		httpmock.NewErrorResponder(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("i/o timeout")}))
+	httpmock.RegisterResponder("POST", "https://up.example/api/query/execute",
+		httpmock.NewStringResponder(200, `{"queryId":"q2","status":"Finished","message":null,"data":{"columns":["a"],"rows":[],"hasNext":false}}`))
+
+	c := NewClient("slow.example", viper.GetString("CONDUIT_TOKEN"))
+	c.Servers = []string{"up.example"}
+	c.nextServer = 0
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); err == nil || !strings.Contains(err.Error(), "i/o timeout") {
+		t.Errorf("Actual: %v\n=====\nExpected: the read timeout", err)
+	}
+	if calls := httpmock.GetCallCountInfo()["POST https://up.example/api/query/execute"]; calls != 0 {
+		t.Errorf("Actual: %v calls to up.example\n=====\nExpected: none, the query may be running on slow.example", calls)
+	}
+	if statuses := c.ServerStatuses(); !statuses[0].Healthy {
+		t.Errorf("A read timeout shouldn't mark the server down: %+v", statuses[0])
+	}
+}
+
+func TestConduitClient_PinnedQuery(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://b.example/api/query/cancel?queryId=q1",
+		httpmock.NewStringResponder(200, `{"isCancelled":true}`))
+
+	c := NewClient("a.example", viper.GetString("CONDUIT_TOKEN"))
+	c.Servers = []string{"b.example"}
+	h := c.AttachOn("b.example", "q1")
+	h.Query.ActiveQueryStatus = StatusRunning
+	if result, err := h.Cancel(context.Background()); err != nil || result.Outcome != CancelConfirmed {
+		t.Errorf("Cancel should have gone to b.example: %+v (%v)", result, err)
+	}
+}
+
+func TestConduitClient_MetadataSpread(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	for _, s := range []string{"a.example", "b.example"} {
+		httpmock.RegisterResponder("GET", "https://"+s+"/api/metadata/databases",
+			httpmock.NewStringResponder(200, `{"databases":["db"]}`))
+	}
+	c := NewClient("a.example", viper.GetString("CONDUIT_TOKEN"))
+	c.Servers = []string{"b.example", "a.example"}
+	for i := 0; i < 4; i++ {
+		c.GetDatabases()
+	}
+	info := httpmock.GetCallCountInfo()
+	if a, b := info["GET https://a.example/api/metadata/databases"], info["GET https://b.example/api/metadata/databases"]; a != 2 || b != 2 {
+		t.Errorf("Actual: %v and %v calls\n=====\nExpected: 2 each", a, b)
+	}
+	for _, s := range c.CheckServers(context.Background()) {
+		if !s.Healthy {
+			t.Errorf("Expected %v to be healthy: %v", s.Server, s.LastError)
+		}
+	}
+}
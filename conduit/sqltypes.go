@@ -0,0 +1,255 @@
+package conduit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SqlType is a JDBC type code, the value Conduit reports in ColumnStruct.SqlType.
+type SqlType int
+
+// JDBC type codes, from java.sql.Types.
+const (
+	SqlTypeBit                   SqlType = -7
+	SqlTypeTinyInt               SqlType = -6
+	SqlTypeSmallInt              SqlType = 5
+	SqlTypeInteger               SqlType = 4
+	SqlTypeBigInt                SqlType = -5
+	SqlTypeFloat                 SqlType = 6
+	SqlTypeReal                  SqlType = 7
+	SqlTypeDouble                SqlType = 8
+	SqlTypeNumeric               SqlType = 2
+	SqlTypeDecimal               SqlType = 3
+	SqlTypeChar                  SqlType = 1
+	SqlTypeVarchar               SqlType = 12
+	SqlTypeLongVarchar           SqlType = -1
+	SqlTypeDate                  SqlType = 91
+	SqlTypeTime                  SqlType = 92
+	SqlTypeTimestamp             SqlType = 93
+	SqlTypeBinary                SqlType = -2
+	SqlTypeVarBinary             SqlType = -3
+	SqlTypeLongVarBinary         SqlType = -4
+	SqlTypeNull                  SqlType = 0
+	SqlTypeOther                 SqlType = 1111
+	SqlTypeJavaObject            SqlType = 2000
+	SqlTypeDistinct              SqlType = 2001
+	SqlTypeStruct                SqlType = 2002
+	SqlTypeArray                 SqlType = 2003
+	SqlTypeBlob                  SqlType = 2004
+	SqlTypeClob                  SqlType = 2005
+	SqlTypeRef                   SqlType = 2006
+	SqlTypeDatalink              SqlType = 70
+	SqlTypeBoolean               SqlType = 16
+	SqlTypeRowId                 SqlType = -8
+	SqlTypeNChar                 SqlType = -15
+	SqlTypeNVarchar              SqlType = -9
+	SqlTypeLongNVarchar          SqlType = -16
+	SqlTypeNClob                 SqlType = 2011
+	SqlTypeSqlXml                SqlType = 2009
+	SqlTypeRefCursor             SqlType = 2012
+	SqlTypeTimeWithTimezone      SqlType = 2013
+	SqlTypeTimestampWithTimezone SqlType = 2014
+)
+
+var sqlTypeNames = map[SqlType]string{
+	SqlTypeBit: "BIT", SqlTypeTinyInt: "TINYINT", SqlTypeSmallInt: "SMALLINT", SqlTypeInteger: "INTEGER",
+	SqlTypeBigInt: "BIGINT", SqlTypeFloat: "FLOAT", SqlTypeReal: "REAL", SqlTypeDouble: "DOUBLE",
+	SqlTypeNumeric: "NUMERIC", SqlTypeDecimal: "DECIMAL", SqlTypeChar: "CHAR", SqlTypeVarchar: "VARCHAR",
+	SqlTypeLongVarchar: "LONGVARCHAR", SqlTypeDate: "DATE", SqlTypeTime: "TIME", SqlTypeTimestamp: "TIMESTAMP",
+	SqlTypeBinary: "BINARY", SqlTypeVarBinary: "VARBINARY", SqlTypeLongVarBinary: "LONGVARBINARY",
+	SqlTypeNull: "NULL", SqlTypeOther: "OTHER", SqlTypeJavaObject: "JAVA_OBJECT", SqlTypeDistinct: "DISTINCT",
+	SqlTypeStruct: "STRUCT", SqlTypeArray: "ARRAY", SqlTypeBlob: "BLOB", SqlTypeClob: "CLOB", SqlTypeRef: "REF",
+	SqlTypeDatalink: "DATALINK", SqlTypeBoolean: "BOOLEAN", SqlTypeRowId: "ROWID", SqlTypeNChar: "NCHAR",
+	SqlTypeNVarchar: "NVARCHAR", SqlTypeLongNVarchar: "LONGNVARCHAR", SqlTypeNClob: "NCLOB", SqlTypeSqlXml: "SQLXML",
+	SqlTypeRefCursor: "REF_CURSOR", SqlTypeTimeWithTimezone: "TIME_WITH_TIMEZONE",
+	SqlTypeTimestampWithTimezone: "TIMESTAMP_WITH_TIMEZONE",
+}
+
+func (t SqlType) String() string {
+	if name, ok := sqlTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("SqlType(%d)", int(t))
+}
+
+// TypeMapping says how values of a column are held in Go.
+type TypeMapping struct {
+	GoType reflect.Type
+	// Nullable is true when GoType can hold a NULL by itself (pointers, slices, interfaces).
+	Nullable bool
+}
+
+var (
+	boolType      = reflect.TypeOf(false)
+	int16Type     = reflect.TypeOf(int16(0))
+	int32Type     = reflect.TypeOf(int32(0))
+	int64Type     = reflect.TypeOf(int64(0))
+	float32Type   = reflect.TypeOf(float32(0))
+	float64Type   = reflect.TypeOf(float64(0))
+	stringType    = reflect.TypeOf("")
+	timeType      = reflect.TypeOf(time.Time{})
+	bytesType     = reflect.TypeOf([]byte(nil))
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// maxInt64Digits is the most decimal digits every value of which fits in an int64.
+const maxInt64Digits = 18
+
+// TypeRegistry maps Conduit columns to Go types. Lookups check, in order, types
+// registered for the column's ColType, types registered for its SqlType, and the
+// built-in names used by sources that report SqlTypeOther (1111), such as nvarchar.
+type TypeRegistry struct {
+	mu        sync.RWMutex
+	colTypes  map[string]TypeMapping
+	sqlTypes  map[SqlType]TypeMapping
+	fallbacks map[string]TypeMapping
+}
+
+// DefaultTypeRegistry is used by code generation and typed decoding unless another registry is given.
+var DefaultTypeRegistry = NewTypeRegistry()
+
+// NewTypeRegistry returns a registry loaded with the standard JDBC mappings.
+func NewTypeRegistry() *TypeRegistry {
+	r := &TypeRegistry{
+		colTypes:  map[string]TypeMapping{},
+		sqlTypes:  map[SqlType]TypeMapping{},
+		fallbacks: map[string]TypeMapping{},
+	}
+	for goType, sqlTypes := range map[reflect.Type][]SqlType{
+		boolType:    {SqlTypeBit, SqlTypeBoolean},
+		int16Type:   {SqlTypeTinyInt, SqlTypeSmallInt}, // TINYINT can be unsigned, overflowing int8
+		int32Type:   {SqlTypeInteger},
+		int64Type:   {SqlTypeBigInt},
+		float32Type: {SqlTypeReal},
+		float64Type: {SqlTypeFloat, SqlTypeDouble, SqlTypeNumeric, SqlTypeDecimal},
+		stringType: {SqlTypeChar, SqlTypeVarchar, SqlTypeLongVarchar, SqlTypeNChar, SqlTypeNVarchar,
+			SqlTypeLongNVarchar, SqlTypeClob, SqlTypeNClob, SqlTypeSqlXml, SqlTypeRowId},
+		timeType:  {SqlTypeDate, SqlTypeTime, SqlTypeTimestamp, SqlTypeTimeWithTimezone, SqlTypeTimestampWithTimezone},
+		bytesType: {SqlTypeBinary, SqlTypeVarBinary, SqlTypeLongVarBinary, SqlTypeBlob},
+	} {
+		for _, t := range sqlTypes {
+			r.sqlTypes[t] = newTypeMapping(goType)
+		}
+	}
+	for goType, names := range map[reflect.Type][]string{
+		boolType:    {"bit", "bool", "boolean"},
+		int16Type:   {"tinyint", "smallint"},
+		int32Type:   {"int", "integer"},
+		int64Type:   {"bigint", "long"},
+		float64Type: {"real", "float", "double", "decimal", "numeric", "number", "money"},
+		stringType: {"char", "nchar", "varchar", "nvarchar", "varchar2", "nvarchar2", "text", "ntext",
+			"string", "uniqueidentifier"},
+		timeType:  {"date", "datetime", "datetime2", "timestamp", "time"},
+		bytesType: {"binary", "varbinary", "blob", "bytes"},
+	} {
+		for _, name := range names {
+			r.fallbacks[name] = newTypeMapping(goType)
+		}
+	}
+	return r
+}
+
+func newTypeMapping(goType reflect.Type) TypeMapping {
+	switch goType.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return TypeMapping{GoType: goType, Nullable: true}
+	}
+	return TypeMapping{GoType: goType}
+}
+
+// RegisterColType maps a source-specific type name, such as nvarchar, to goType.
+// It takes precedence over the SqlType mapping. Names are case-insensitive.
+func (r *TypeRegistry) RegisterColType(colType string, goType reflect.Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.colTypes[strings.ToLower(colType)] = newTypeMapping(goType)
+}
+
+// RegisterSqlType maps a JDBC type code to goType.
+func (r *TypeRegistry) RegisterSqlType(t SqlType, goType reflect.Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sqlTypes[t] = newTypeMapping(goType)
+}
+
+// Lookup returns the Go type for a column. NUMERIC and DECIMAL columns with a scale
+// of 0, whether known by their SqlType or their ColType, map to int64 when their
+// precision is at most 18 digits, and to string when it's more or not known, as int64
+// would overflow. Unknown types map to interface{}.
+func (r *TypeRegistry) Lookup(c ColumnStruct) TypeMapping {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	colType := strings.ToLower(c.ColType)
+	if m, ok := r.colTypes[colType]; ok {
+		return m
+	}
+	if m, ok := r.sqlTypes[SqlType(c.SqlType)]; ok {
+		return wholeDecimal(c, m)
+	}
+	if m, ok := r.fallbacks[colType]; ok {
+		return wholeDecimal(c, m)
+	}
+	return newTypeMapping(interfaceType)
+}
+
+// wholeDecimal narrows the float64 mapping of a scale 0 NUMERIC or DECIMAL column.
+func wholeDecimal(c ColumnStruct, m TypeMapping) TypeMapping {
+	if !c.isDecimal() || m.GoType != float64Type || c.ScaleOpt == nil || *c.ScaleOpt != 0 {
+		return m
+	}
+	if c.LengthOpt != nil && *c.LengthOpt <= maxInt64Digits {
+		return newTypeMapping(int64Type)
+	}
+	return newTypeMapping(stringType)
+}
+
+// isDecimal reports whether the column is NUMERIC or DECIMAL by its SqlType or ColType.
+func (c ColumnStruct) isDecimal() bool {
+	if t := SqlType(c.SqlType); t == SqlTypeNumeric || t == SqlTypeDecimal {
+		return true
+	}
+	switch strings.ToLower(c.ColType) {
+	case "decimal", "numeric", "number":
+		return true
+	}
+	return false
+}
+
+// GoType returns the Go type for a column. With nullable set, types that can't
+// hold a NULL themselves come back as pointers.
+func (r *TypeRegistry) GoType(c ColumnStruct, nullable bool) reflect.Type {
+	m := r.Lookup(c)
+	if nullable && !m.Nullable {
+		return reflect.PtrTo(m.GoType)
+	}
+	return m.GoType
+}
+
+// GoType is the type DefaultTypeRegistry gives the column, as a pointer when the server
+// reports the column as nullable and the type can't hold a NULL itself.
+func (c ColumnStruct) GoType() reflect.Type {
+	return DefaultTypeRegistry.GoType(c, c.Nullable != nil && *c.Nullable)
+}
+
+// IsNumeric reports whether the column is NUMERIC or DECIMAL, or DefaultTypeRegistry
+// maps it to an integer or float.
+func (c ColumnStruct) IsNumeric() bool {
+	if c.isDecimal() {
+		return true
+	}
+	switch DefaultTypeRegistry.Lookup(c).GoType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// IsTemporal reports whether DefaultTypeRegistry maps the column to time.Time.
+func (c ColumnStruct) IsTemporal() bool {
+	return DefaultTypeRegistry.Lookup(c).GoType == timeType
+}
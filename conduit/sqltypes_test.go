@@ -0,0 +1,75 @@
+package conduit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func opt(n int) *int { return &n }
+
+func TestTypeRegistry_Lookup(t *testing.T) {
+	r := NewTypeRegistry()
+	cases := []struct {
+		column   ColumnStruct
+		expected reflect.Type
+	}{
+		{ColumnStruct{Name: "code", ColType: "int", SqlType: 4}, reflect.TypeOf(int32(0))},
+		{ColumnStruct{Name: "city", ColType: "nvarchar", SqlType: 1111}, reflect.TypeOf("")},
+		{ColumnStruct{Name: "amount", ColType: "decimal", SqlType: 3, ScaleOpt: opt(2)}, reflect.TypeOf(float64(0))},
+		{ColumnStruct{Name: "id", ColType: "number", SqlType: 2, LengthOpt: opt(18), ScaleOpt: opt(0)}, reflect.TypeOf(int64(0))},
+		{ColumnStruct{Name: "big_id", ColType: "number", SqlType: 2, LengthOpt: opt(38), ScaleOpt: opt(0)}, reflect.TypeOf("")},
+		{ColumnStruct{Name: "any_id", ColType: "decimal", SqlType: 3, ScaleOpt: opt(0)}, reflect.TypeOf("")},
+		{ColumnStruct{Name: "order_id", ColType: "NUMERIC", LengthOpt: opt(10), ScaleOpt: opt(0)}, reflect.TypeOf(int64(0))},
+		{ColumnStruct{Name: "ledger_id", ColType: "decimal", LengthOpt: opt(30), ScaleOpt: opt(0)}, reflect.TypeOf("")},
+		{ColumnStruct{Name: "rate", ColType: "decimal", LengthOpt: opt(10), ScaleOpt: opt(4)}, reflect.TypeOf(float64(0))},
+		{ColumnStruct{Name: "stock", ColType: "tinyint", SqlType: -6}, reflect.TypeOf(int16(0))},
+		{ColumnStruct{Name: "departed", ColType: "timestamp", SqlType: 93}, reflect.TypeOf(time.Time{})},
+		{ColumnStruct{Name: "shape", ColType: "geometry", SqlType: 1111}, reflect.TypeOf((*interface{})(nil)).Elem()},
+	}
+	for _, c := range cases {
+		if actual := r.Lookup(c.column).GoType; actual != c.expected {
+			t.Errorf("%v: Actual: %v Expected: %v", c.column.Name, actual, c.expected)
+		}
+	}
+}
+
+func TestTypeRegistry_Overrides(t *testing.T) {
+	r := NewTypeRegistry()
+	col := ColumnStruct{Name: "city", ColType: "NVARCHAR", SqlType: 1111}
+	r.RegisterColType("nvarchar", reflect.TypeOf([]byte(nil)))
+	if m := r.Lookup(col); m.GoType != reflect.TypeOf([]byte(nil)) || !m.Nullable {
+		t.Errorf("ColType override not used: %+v", m)
+	}
+	r.RegisterSqlType(SqlTypeInteger, reflect.TypeOf(int64(0)))
+	if actual := r.GoType(ColumnStruct{ColType: "int", SqlType: 4}, true); actual != reflect.TypeOf((*int64)(nil)) {
+		t.Errorf("Actual: %v Expected: *int64", actual)
+	}
+	if DefaultTypeRegistry.Lookup(ColumnStruct{ColType: "int", SqlType: 4}).GoType != reflect.TypeOf(int32(0)) {
+		t.Errorf("Overriding a new registry changed the default one")
+	}
+}
+
+func TestSqlType_String(t *testing.T) {
+	if SqlTypeOther.String() != "OTHER" || SqlType(4242).String() != "SqlType(4242)" {
+		t.Errorf("Unexpected names: %v, %v", SqlTypeOther, SqlType(4242))
+	}
+}
+
+func TestColumnStruct_Helpers(t *testing.T) {
+	notNull, null := false, true
+	fare := ColumnStruct{ColType: "decimal", SqlType: 3, LengthOpt: opt(10), ScaleOpt: opt(2), Nullable: &notNull}
+	if !fare.IsNumeric() || fare.IsTemporal() || fare.GoType() != reflect.TypeOf(float64(0)) {
+		t.Errorf("Unexpected helpers for %+v: %v", fare, fare.GoType())
+	}
+	opened := ColumnStruct{ColType: "date", SqlType: 1111, Nullable: &null}
+	if opened.IsNumeric() || !opened.IsTemporal() || opened.GoType() != reflect.TypeOf((*time.Time)(nil)) {
+		t.Errorf("Unexpected helpers for %+v: %v", opened, opened.GoType())
+	}
+	if flag := (ColumnStruct{ColType: "bit", SqlType: -7}); flag.IsNumeric() {
+		t.Errorf("bit columns are bools, not numbers")
+	}
+	if wide := (ColumnStruct{ColType: "decimal", SqlType: 3, LengthOpt: opt(38), ScaleOpt: opt(0)}); !wide.IsNumeric() || wide.GoType() != reflect.TypeOf("") {
+		t.Errorf("DECIMAL(38,0) should be numeric, held in a string: %v", wide.GoType())
+	}
+}
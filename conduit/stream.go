@@ -0,0 +1,219 @@
+package conduit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamQueryResult decodes one result page from r as it is read, handing each row to
+// row as soon as it's parsed instead of keeping it in ParsedRows. Pass a nil row to keep
+// them. Errors from row stop the decoding and are returned as they are; anything else
+// that goes wrong is a *DecodeError. Pages decoded this way keep RawData.Columns but not
+// RawData.Rows, which would be another copy of every row.
+func StreamQueryResult(r io.Reader, row func(map[string]interface{}) error) (QueryResultStruct, error) {
+	if row == nil {
+		return decodeQueryPage(r, false, nil)
+	}
+	return decodeQueryPage(r, false, func(columns []string, r map[string]interface{}) error {
+		return row(r)
+	})
+}
+
+// decodeQueryPage is StreamQueryResult, optionally rejecting fields it doesn't know, and
+// handing row the page's columns along with each row. They are nil if Conduit sent the
+// rows before the columns.
+func decodeQueryPage(r io.Reader, strict bool, row func(columns []string, row map[string]interface{}) error) (QueryResultStruct, error) {
+	seen := &payloadRecorder{}
+	d := json.NewDecoder(io.TeeReader(r, seen))
+	// Read numbers as they were sent, so BIGINTs past 2^53 can still be scanned exactly.
+	d.UseNumber()
+	p := &pageDecoder{d: d, strict: strict, row: row}
+	var qrs QueryResultStruct
+	err := p.page(&qrs)
+	if err == nil {
+		if _, err = p.d.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errors.New("invalid data after the result")
+		}
+	}
+	if err == nil {
+		return qrs, nil
+	}
+	if e, ok := err.(rowError); ok {
+		return qrs, e.err
+	}
+	if errors.Is(err, ErrResponseTooLarge) {
+		return qrs, err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	offset := p.d.InputOffset()
+	return qrs, &DecodeError{Offset: offset, Snippet: seen.around(offset), Err: err}
+}
+
+// rowError carries an error from the row callback through the decoder untouched.
+type rowError struct {
+	err error
+}
+
+func (e rowError) Error() string {
+	return e.err.Error()
+}
+
+type pageDecoder struct {
+	d      *json.Decoder
+	strict bool
+	row    func(columns []string, row map[string]interface{}) error
+}
+
+func (p *pageDecoder) page(qrs *QueryResultStruct) error {
+	return p.object("", func(key string) error {
+		switch strings.ToLower(key) {
+		case "queryid":
+			return p.d.Decode(&qrs.QueryId)
+		case "status":
+			return p.d.Decode(&qrs.Status)
+		case "message":
+			return p.d.Decode(&qrs.Message)
+		case "data":
+			return p.data(qrs)
+		}
+		return p.skip(key)
+	})
+}
+
+func (p *pageDecoder) data(qrs *QueryResultStruct) error {
+	return p.object("data", func(key string) error {
+		switch strings.ToLower(key) {
+		case "hasnext":
+			return p.d.Decode(&qrs.RawData.HasNext)
+		case "hasprevious":
+			return p.d.Decode(&qrs.RawData.HasPrevious)
+		case "columns":
+			var columns json.RawMessage
+			if err := p.d.Decode(&columns); err != nil {
+				return err
+			}
+			if err := json.Unmarshal(columns, &qrs.ParsedColumns); err != nil {
+				return fmt.Errorf("data.columns: %v", err)
+			}
+			if string(columns) != "null" {
+				qrs.RawData.Columns = &columns
+			}
+			return nil
+		case "rows":
+			return p.rows(qrs)
+		}
+		return p.skip("data." + key)
+	})
+}
+
+// rows decodes the rows array one row at a time.
+func (p *pageDecoder) rows(qrs *QueryResultStruct) error {
+	t, err := p.d.Token()
+	if err != nil || t == nil {
+		return err
+	}
+	if t != json.Delim('[') {
+		return fmt.Errorf("data.rows: expected an array, not %v", t)
+	}
+	for p.d.More() {
+		var row map[string]interface{}
+		if err := p.d.Decode(&row); err != nil {
+			return fmt.Errorf("data.rows: %w", err)
+		}
+		if p.row == nil {
+			qrs.addRow(row)
+			continue
+		}
+		plainNumbers(row)
+		if err := p.row(qrs.ParsedColumns, row); err != nil {
+			return rowError{err}
+		}
+	}
+	_, err = p.d.Token()
+	return err
+}
+
+// object reads a JSON object, or null, calling field with each key while the decoder is
+// at its value.
+func (p *pageDecoder) object(path string, field func(key string) error) error {
+	t, err := p.d.Token()
+	if err != nil || t == nil {
+		return err
+	}
+	if t != json.Delim('{') {
+		if path == "" {
+			return fmt.Errorf("expected a query result object, not %v", t)
+		}
+		return fmt.Errorf("%v: expected an object, not %v", path, t)
+	}
+	for p.d.More() {
+		t, err := p.d.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(t.(string)); err != nil {
+			return err
+		}
+	}
+	_, err = p.d.Token()
+	return err
+}
+
+// skip passes over the value of a field this decoder doesn't know, or rejects it when strict.
+func (p *pageDecoder) skip(path string) error {
+	if p.strict {
+		return fmt.Errorf("unknown field %v", path)
+	}
+	var ignored json.RawMessage
+	return p.d.Decode(&ignored)
+}
+
+// tailBytes is how much of the end of a streamed payload is kept for error snippets.
+// Decoders read ahead, so it's more than a snippet.
+const tailBytes = 4096
+
+// payloadRecorder keeps the start and the latest part of a payload being streamed, so
+// a DecodeError can quote it without the whole payload being held.
+type payloadRecorder struct {
+	head []byte
+	tail []byte
+	n    int64
+}
+
+func (r *payloadRecorder) Write(p []byte) (int, error) {
+	if room := snippetBytes - len(r.head); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		r.head = append(r.head, p[:room]...)
+	}
+	if len(p) >= tailBytes {
+		r.tail = append(r.tail[:0], p[len(p)-tailBytes:]...)
+	} else {
+		if over := len(r.tail) + len(p) - tailBytes; over > 0 {
+			r.tail = r.tail[:copy(r.tail, r.tail[over:])]
+		}
+		r.tail = append(r.tail, p...)
+	}
+	r.n += int64(len(p))
+	return len(p), nil
+}
+
+// around quotes the payload near offset, from whichever part of it was kept.
+func (r *payloadRecorder) around(offset int64) string {
+	tailStart := r.n - int64(len(r.tail))
+	switch {
+	case offset >= tailStart:
+		return snippet(r.tail, offset-tailStart)
+	case offset < int64(len(r.head)):
+		return snippet(r.head, offset)
+	}
+	return snippet(r.tail, 0)
+}
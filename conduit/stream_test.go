@@ -0,0 +1,88 @@
+package conduit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/spf13/viper"
+)
+
+func TestStreamQueryResult(t *testing.T) {
+	payload := `{"queryId":"q1","extra":{"ignored":[1,2]},"status":"Finished","message":null,"data":{"rows":[{"a":1},{"a":2},{"a":3}],"columns":["a"],"hasNext":true}}`
+	var seen []float64
+	qrs, err := StreamQueryResult(strings.NewReader(payload), func(row map[string]interface{}) error {
+		seen = append(seen, row["a"].(float64))
+		return nil
+	})
+	if err != nil || len(seen) != 3 || qrs.ParsedRows != nil || qrs.QueryId != "q1" || !qrs.RawData.HasNext || len(qrs.ParsedColumns) != 1 {
+		t.Errorf("Unexpected result: %+v, rows %v (%v)", qrs, seen, err)
+	}
+
+	stop := errors.New("enough")
+	_, err = StreamQueryResult(strings.NewReader(payload), func(map[string]interface{}) error { return stop })
+	if err != stop {
+		t.Errorf("Actual: %v\n=====\nExpected: the callback's error", err)
+	}
+
+	qrs, err = StreamQueryResult(strings.NewReader(payload), nil)
+	if err != nil || len(qrs.ParsedRows) != 3 || qrs.RawData.Rows != nil {
+		t.Errorf("Rows should be kept in ParsedRows only: %+v (%v)", qrs, err)
+	}
+	if _, err := decodeQueryPage(strings.NewReader(payload), true, nil); err == nil || !strings.Contains(err.Error(), "unknown field extra") {
+		t.Errorf("Actual: %v\n=====\nExpected: unknown field extra", err)
+	}
+}
+
+func TestStreamQueryResult_Errors(t *testing.T) {
+	// A bad row far into a page is quoted from where it is.
+	var rows []string
+	for i := 0; i < 2000; i++ {
+		rows = append(rows, fmt.Sprintf(`{"a":%d}`, i))
+	}
+	payload := `{"queryId":"q1","data":{"columns":["a"],"rows":[` + strings.Join(rows, ",") + `,{"a":oops}]}}`
+	_, err := StreamQueryResult(strings.NewReader(payload), nil)
+	decodeErr, ok := err.(*DecodeError)
+	if !ok || !strings.Contains(decodeErr.Snippet, "oops") || decodeErr.Offset < 20000 {
+		t.Errorf("Actual: %v\n=====\nExpected: a *DecodeError quoting the bad row", err)
+	}
+	cases := map[string]string{
+		``:                               "unexpected EOF",
+		`<html>`:                         "invalid character '<'",
+		`[1]`:                            "expected a query result object",
+		`{"data":{"rows":{}}}`:           "data.rows: expected an array",
+		`{"data":{"rows":[1]}}`:          "data.rows: json: cannot unmarshal number",
+		`{"queryId":"q1"} {"queryId":2}`: "invalid data after the result",
+	}
+	for payload, expected := range cases {
+		_, err := StreamQueryResult(strings.NewReader(payload), nil)
+		if _, ok := err.(*DecodeError); !ok || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q\nActual: %v\n=====\nExpected: a *DecodeError mentioning %v", payload, err, expected)
+		}
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	page := `{"queryId":"q1","status":"Finished","data":{"columns":["a"],"rows":[{"a":"` + strings.Repeat("x", 1000) + `"}],"hasNext":false}}`
+	url := fmt.Sprintf("https://%v/api/query/execute", viper.GetString("CONDUIT_SERVER"))
+	httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(200, page))
+
+	c := NewClient(viper.GetString("CONDUIT_SERVER"), viper.GetString("CONDUIT_TOKEN"))
+	c.MaxResponseSize = 500
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Actual: %v\n=====\nExpected: %v", err, ErrResponseTooLarge)
+	}
+	c.MaxResponseSize = int64(len(page))
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); err != nil || len(c.Query.QueryResults[0].ParsedRows) != 1 {
+		t.Errorf("A page of exactly MaxResponseSize should be read: %v", err)
+	}
+	c.MaxResponseSize = -1
+	if err := c.ExecuteQuery(context.Background(), "SELECT a FROM t"); err != nil {
+		t.Errorf("Unexpected error without a limit: %v", err)
+	}
+}
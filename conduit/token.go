@@ -0,0 +1,251 @@
+package conduit
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// TokenSource supplies the token sent to Conduit.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a token given directly.
+type StaticToken string
+
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// CommandToken runs Command with sh -c and uses what it prints, such as
+// "vault read -field=token secret/conduit".
+type CommandToken struct {
+	Command string
+}
+
+func (t CommandToken) Token(ctx context.Context) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", t.Command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command %q: %v %v", t.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	return nonEmptyToken(string(out), fmt.Sprintf("token command %q", t.Command))
+}
+
+// FileToken reads the token from a file, which must not be readable by other users.
+type FileToken struct {
+	Path string
+}
+
+func (t FileToken) Token(ctx context.Context) (string, error) {
+	if err := checkPrivate(t.Path); err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(t.Path)
+	if err != nil {
+		return "", err
+	}
+	return nonEmptyToken(string(b), t.Path)
+}
+
+// KeyringToken looks the token up in the OS keyring through the Secret Service, using
+// secret-tool (from libsecret) or a stand-in that takes the same arguments:
+//
+//	secret-tool store --label "Conduit token" service conduit account default
+type KeyringToken struct {
+	Service string // defaults to "conduit"
+	Account string // defaults to "default"
+	Tool    string // defaults to "secret-tool"
+}
+
+func (t KeyringToken) Token(ctx context.Context) (string, error) {
+	tool, service, account := t.Tool, t.Service, t.Account
+	if tool == "" {
+		tool = "secret-tool"
+	}
+	if service == "" {
+		service = "conduit"
+	}
+	if account == "" {
+		account = "default"
+	}
+	out, err := exec.CommandContext(ctx, tool, "lookup", "service", service, "account", account).Output()
+	if err != nil {
+		return "", fmt.Errorf("looking up %v/%v in the keyring with %v: %v", service, account, tool, err)
+	}
+	return nonEmptyToken(string(out), fmt.Sprintf("keyring entry %v/%v", service, account))
+}
+
+// EncryptedFileToken decrypts a token written by WriteEncryptedToken, with a key derived
+// from Passphrase. The file must not be readable by other users.
+type EncryptedFileToken struct {
+	Path       string
+	Passphrase string
+}
+
+// encryptedToken is the file layout: AES-256-GCM with a PBKDF2-SHA256 key.
+type encryptedToken struct {
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+const encryptedTokenIterations = 200000
+
+func (t EncryptedFileToken) Token(ctx context.Context) (string, error) {
+	if t.Passphrase == "" {
+		return "", fmt.Errorf("no passphrase for %v", t.Path)
+	}
+	if err := checkPrivate(t.Path); err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(t.Path)
+	if err != nil {
+		return "", err
+	}
+	var f encryptedToken
+	if err := json.Unmarshal(b, &f); err != nil {
+		return "", fmt.Errorf("%v is not an encrypted token file: %v", t.Path, err)
+	}
+	if f.KDF != "pbkdf2-sha256" || f.Iterations < 1 {
+		return "", fmt.Errorf("%v uses unsupported key derivation %q", t.Path, f.KDF)
+	}
+	gcm, err := tokenCipher(t.Passphrase, f.Salt, f.Iterations)
+	if err != nil {
+		return "", err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return "", fmt.Errorf("%v has a bad nonce", t.Path)
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt %v: wrong passphrase or corrupt file", t.Path)
+	}
+	return nonEmptyToken(string(plain), t.Path)
+}
+
+// WriteEncryptedToken encrypts token with passphrase into path, readable only by its owner.
+func WriteEncryptedToken(path, passphrase, token string) error {
+	if passphrase == "" {
+		return fmt.Errorf("a passphrase is needed to encrypt the token")
+	}
+	f := encryptedToken{KDF: "pbkdf2-sha256", Iterations: encryptedTokenIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := tokenCipher(passphrase, f.Salt, f.Iterations)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, []byte(token), nil)
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(path, b)
+}
+
+func tokenCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// checkPrivate rejects a secret file that its group or other users can read or write.
+func checkPrivate(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%v has permissions %v, it must only be accessible by its owner (chmod 600 %v)", path, info.Mode().Perm(), path)
+	}
+	return nil
+}
+
+// writePrivateFile writes b to path with mode 0600, replacing any file already there.
+func writePrivateFile(path string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".conduit-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func nonEmptyToken(s, from string) (string, error) {
+	token := strings.TrimSpace(s)
+	if token == "" {
+		return "", fmt.Errorf("no token in %v", from)
+	}
+	return token, nil
+}
+
+// TokenCacheTTL is how long a client made by Config.NewClient reuses a token before asking
+// its source again.
+var TokenCacheTTL = 5 * time.Minute
+
+// CachedToken reuses src's token for ttl before asking again, so a slow source such as
+// a command isn't run for every request.
+func CachedToken(src TokenSource, ttl time.Duration) TokenSource {
+	return &cachedToken{src: src, ttl: ttl}
+}
+
+type cachedToken struct {
+	mu      sync.Mutex
+	src     TokenSource
+	ttl     time.Duration
+	token   string
+	fetched time.Time
+}
+
+func (t *cachedToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Since(t.fetched) < t.ttl {
+		return t.token, nil
+	}
+	token, err := t.src.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token, t.fetched = token, time.Now()
+	return token, nil
+}
@@ -0,0 +1,112 @@
+package conduit
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "conduit-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestTokenSources(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+
+	if token, err := (CommandToken{Command: "echo '  fromcommand '"}).Token(ctx); err != nil || token != "fromcommand" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: fromcommand", token, err)
+	}
+	if _, err := (CommandToken{Command: "true"}).Token(ctx); err == nil {
+		t.Errorf("Expected an error for a command printing nothing")
+	}
+
+	file := filepath.Join(dir, "token")
+	ioutil.WriteFile(file, []byte("fromfile\n"), 0600)
+	if token, err := (FileToken{Path: file}).Token(ctx); err != nil || token != "fromfile" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: fromfile", token, err)
+	}
+	os.Chmod(file, 0644)
+	if _, err := (FileToken{Path: file}).Token(ctx); err == nil {
+		t.Errorf("Expected a world-readable token file to be rejected")
+	}
+
+	// A stand-in for secret-tool that answers from its arguments.
+	tool := filepath.Join(dir, "secret-tool")
+	ioutil.WriteFile(tool, []byte("#!/bin/sh\n[ \"$1\" = lookup ] && echo \"$3-$5\"\n"), 0700)
+	if token, err := (KeyringToken{Tool: tool}).Token(ctx); err != nil || token != "conduit-default" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: conduit-default", token, err)
+	}
+	if token, err := (KeyringToken{Tool: tool, Service: "svc", Account: "me"}).Token(ctx); err != nil || token != "svc-me" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: svc-me", token, err)
+	}
+}
+
+func TestEncryptedFileToken(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(tempDir(t), "token.enc")
+	if err := WriteEncryptedToken(file, "correct horse", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(file)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Actual: %v\n=====\nExpected: -rw-------", info.Mode().Perm())
+	}
+	if token, err := (EncryptedFileToken{Path: file, Passphrase: "correct horse"}).Token(ctx); err != nil || token != "s3cret" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: s3cret", token, err)
+	}
+	if _, err := (EncryptedFileToken{Path: file, Passphrase: "wrong"}).Token(ctx); err == nil {
+		t.Errorf("Expected the wrong passphrase to fail")
+	}
+}
+
+func TestConfig_TokenSource(t *testing.T) {
+	if _, err := (&Config{Token: "a", TokenCommand: "echo b"}).TokenSource(); err == nil {
+		t.Errorf("Expected an error for two token sources")
+	}
+	if src, err := (&Config{}).TokenSource(); src != nil || err != nil {
+		t.Errorf("Actual: %v (%v)\n=====\nExpected: no source", src, err)
+	}
+	if token, err := (&Config{TokenCommand: "echo b"}).ResolveToken(); err != nil || token != "b" {
+		t.Errorf("Actual: %q (%v)\n=====\nExpected: b", token, err)
+	}
+}
+
+func TestConduitClient_TokenSource(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", "https://blah/api/metadata/databases",
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "Bearer rotated" {
+				return httpmock.NewStringResponse(401, `{}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"databases":["db"]}`), nil
+		})
+	c := NewClient("blah", "stale")
+	runs := 0
+	c.TokenSource = CachedToken(tokenFunc(func() string { runs++; return "rotated" }), 1<<62)
+	for i := 0; i < 3; i++ {
+		if dbs := c.GetDatabases(); len(dbs.Databases) != 1 {
+			t.Fatalf("Expected the token from TokenSource to be sent")
+		}
+	}
+	if runs != 1 {
+		t.Errorf("Actual: %v fetches\n=====\nExpected: 1, cached", runs)
+	}
+}
+
+type tokenFunc func() string
+
+func (f tokenFunc) Token(ctx context.Context) (string, error) {
+	return f(), nil
+}
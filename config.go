@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	conduitclient "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+)
+
+// runConfig implements the "config" subcommand. "config show" prints the settings the
+// other commands would use, with secrets redacted, and where each one came from.
+func runConfig(cfg *conduitclient.Config, args []string) error {
+	if len(args) == 0 || args[0] != "show" {
+		return fmt.Errorf("usage: config show")
+	}
+	shown := cfg.Redacted()
+	source := func(name string) string {
+		if s, ok := shown.Sources[name]; ok {
+			return "  # from " + s
+		}
+		return ""
+	}
+	fmt.Printf("# file: %v\n", valueOr(shown.File, "(none)"))
+	fmt.Printf("# profile: %v\n", valueOr(shown.Profile, "(none)"))
+	if profiles, err := conduitclient.Profiles(shown.File); err == nil && len(profiles) > 0 {
+		fmt.Printf("# profiles: %v\n", strings.Join(profiles, ", "))
+	}
+	settings := []struct {
+		name  string
+		value interface{}
+	}{
+		{"server", shown.Server},
+		{"servers", shown.Servers},
+		{"token", shown.Token},
+		{"token_command", shown.TokenCommand},
+		{"token_file", shown.TokenFile},
+		{"encrypted_token_file", shown.EncryptedTokenFile},
+		{"keyring_service", shown.KeyringService},
+		{"keyring_account", shown.KeyringAccount},
+		{"page_size", shown.PageSize},
+		{"timeout", shown.Timeout},
+		{"tls.ca_file", shown.TLS.CAFile},
+		{"tls.cert_file", shown.TLS.CertFile},
+		{"tls.key_file", shown.TLS.KeyFile},
+		{"tls.key_passphrase", shown.TLS.KeyPassphrase},
+		{"tls.server_name", shown.TLS.ServerName},
+		{"tls.insecure_skip_verify", shown.TLS.SkipVerify()},
+	}
+	for _, s := range settings {
+		if _, ok := shown.Sources[s.name]; !ok {
+			continue
+		}
+		switch v := s.value.(type) {
+		case string:
+			fmt.Printf("%v = %q%v\n", s.name, v, source(s.name))
+		case []string:
+			quoted := make([]string, len(v))
+			for i, server := range v {
+				quoted[i] = fmt.Sprintf("%q", server)
+			}
+			fmt.Printf("%v = [%v]%v\n", s.name, strings.Join(quoted, ", "), source(s.name))
+		case time.Duration:
+			fmt.Printf("%v = %q%v\n", s.name, v.String(), source(s.name))
+		default:
+			fmt.Printf("%v = %v%v\n", s.name, v, source(s.name))
+		}
+	}
+	var unset []string
+	for _, s := range settings {
+		if _, ok := shown.Sources[s.name]; !ok {
+			unset = append(unset, s.name)
+		}
+	}
+	sort.Strings(unset)
+	if len(unset) > 0 {
+		fmt.Printf("# not set: %v\n", strings.Join(unset, ", "))
+	}
+	return nil
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
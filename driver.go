@@ -1,56 +1,121 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	conduitclient "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
-	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/pflag"
-	"github.com/spf13/viper"
 	"log"
 	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
-func initConfig() (err error) {
+// initConfig parses the global flags and loads the settings for the chosen profile.
+// See conduitclient.LoadConfig for the order settings are taken in.
+func initConfig() (*conduitclient.Config, error) {
 	log.Printf("Starting Conduit Client, getting config")
-	// Find home directory.
-	home, err := homedir.Dir()
-	if err != nil {
-		fmt.Println(err)
-		return err
-	}
-	viper.AddConfigPath(home)
-	viper.SetConfigType("toml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("~")
-	viper.SetConfigName("ConduitClient.toml")
-	pflag.String("CONDUIT_SERVER","", "This is the CONDUIT Server to use.")
+	profile := pflag.String("profile", "", "Profile in the config file to use (default: $CONDUIT_PROFILE, or the file's default_profile).")
+	configFile := pflag.String("config", "", "Config file to read (default: $CONDUIT_CONFIG, or "+conduitclient.ConfigFileName+" here or in your home directory).")
+	pflag.String("server", "", "The Conduit server to use.")
+	pflag.String("token", "", "The Conduit token to use.")
+	pflag.String("token-command", "", "Command printing the Conduit token to use.")
+	pflag.String("token-file", "", "File holding the Conduit token to use, readable only by you.")
+	pflag.Int("page-size", 0, "Rows per page.")
+	pflag.String("timeout", "", "Query timeout, such as 90s or 5m.")
+	pflag.String("CONDUIT_SERVER", "", "This is the CONDUIT Server to use.")
 	pflag.String("CONDUIT_TOKEN", "", "This is the CONDUIT Token to use.")
+	pflag.CommandLine.MarkDeprecated("CONDUIT_SERVER", "use --server")
+	pflag.CommandLine.MarkDeprecated("CONDUIT_TOKEN", "use --token")
+	pflag.CommandLine.SetInterspersed(false)
 	pflag.Parse()
-	viper.BindPFlags(pflag.CommandLine)
-	viper.AutomaticEnv()
-	err = viper.ReadInConfig()
+	cfg, err := conduitclient.LoadConfig(conduitclient.LoadOptions{
+		Profile:    *profile,
+		File:       *configFile,
+		Flags:      pflag.CommandLine,
+		NewProfile: pflag.Arg(0) == "login",
+	})
 	if err != nil {
-		fmt.Println("No config file found in current directory or home directory (ConduitClient.toml). Will use command-line args and envvars.")
-		err = nil
+		return nil, err
+	}
+	if cfg.File == "" {
+		fmt.Println("No config file found in current directory or home directory (" + conduitclient.ConfigFileName + "). Will use command-line args and envvars.")
 	}
-	return err
+	return cfg, nil
+}
+
+// closeClient cancels whatever queries the client still has running on Conduit.
+func closeClient(client *conduitclient.ConduitClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		log.Printf("Shutting down: %v", err.Error())
+	}
+}
+
+// closeOnSignal closes the client and exits on SIGINT or SIGTERM, so queries started
+// here don't keep running on Conduit after the process is gone.
+func closeOnSignal(client *conduitclient.ConduitClient) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %v, cancelling in-flight queries...", sig)
+		closeClient(client)
+		os.Exit(1)
+	}()
 }
 
 func main() {
 	rand.Seed(time.Now().Unix())
-	err := initConfig()
+	cfg, err := initConfig()
 	if err == nil {
 		log.Printf("Initialized...")
-		client := conduitclient.NewClient(
-			viper.GetString("CONDUIT_SERVER"),
-			viper.GetString("CONDUIT_TOKEN"))
+		// These commands work without a usable server and token.
+		if args := pflag.Args(); len(args) > 0 {
+			commands := map[string]func(*conduitclient.Config, []string) error{
+				"config": runConfig,
+				"login":  runLogin,
+				"logout": runLogout,
+			}
+			if command, ok := commands[args[0]]; ok {
+				if err := command(cfg, args[1:]); err != nil {
+					log.Fatalln(err.Error())
+				}
+				return
+			}
+		}
+		client, err := cfg.NewClient()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
 		client.Print()
+		closeOnSignal(client)
+		defer closeClient(client)
+		if args := pflag.Args(); len(args) > 0 {
+			switch args[0] {
+			case "generate":
+				err = runGenerate(client, args[1:])
+			case "health":
+				err = runHealth(client, args[1:])
+			case "databases", "tables", "schema", "query":
+				err = runShow(client, args[0], args[1:])
+			default:
+				err = fmt.Errorf("unknown command %q", args[0])
+			}
+			if err != nil {
+				closeClient(client)
+				log.Fatalln(err.Error())
+			}
+			return
+		}
 		//dbs := client.GetDatabases()
 		//dbs.Print()
 		//tables := client.GetTables("dynamics365_crm")
 		//tables.Print()
-		//err = client.ExecuteQuery("SELECT * FROM `oracle_flights`.`PDBADMIN___FLIGHTS` LIMIT 100", 10000, 10)
+		//err = client.ExecuteQuery(context.Background(), "SELECT * FROM `oracle_flights`.`PDBADMIN___FLIGHTS` WHERE ORIGIN = ? LIMIT 100", "SEA")
 		//if err != nil {
 		//	log.Fatalf(err.Error())
 		//} else {
@@ -65,4 +130,3 @@ func main() {
 		log.Fatalln(err.Error())
 	}
 }
-
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	conduitclient "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+	"github.com/spf13/pflag"
+)
+
+// runGenerate implements the "generate" subcommand. It is meant to be used from a
+// go:generate directive, for example:
+//
+//	//go:generate go run github.com/BlueprintConsulting/Conduit-GoSDK generate --database oracle_flights --table PDBADMIN___FLIGHTS --out flights.go
+func runGenerate(client *conduitclient.ConduitClient, args []string) error {
+	flags := pflag.NewFlagSet("generate", pflag.ContinueOnError)
+	database := flags.String("database", "", "Conduit database holding the tables.")
+	tables := flags.StringSlice("table", nil, "Tables to generate structs for (default: every table in the database).")
+	pkg := flags.String("package", "", "Package name for the generated file (default: $GOPACKAGE, or models).")
+	out := flags.String("out", "", "File to write (default: stdout).")
+	consts := flags.Bool("constants", true, "Emit table and column name constants.")
+	helpers := flags.Bool("helpers", true, "Emit a typed Query helper per table.")
+	nullable := flags.Bool("nullable", false, "Use pointer fields for columns that may be NULL.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *database == "" {
+		return fmt.Errorf("generate needs --database")
+	}
+	if len(*tables) == 0 {
+		for _, t := range client.GetTables(*database).Tables {
+			*tables = append(*tables, t.Table)
+		}
+	}
+	if *pkg == "" {
+		*pkg = os.Getenv("GOPACKAGE")
+	}
+	var schemas []*conduitclient.TableSchemaStruct
+	for _, t := range *tables {
+		schemas = append(schemas, client.GetTableSchema(*database, strings.TrimSpace(t)))
+	}
+
+	// Generate in memory, so a failed run leaves an existing --out file as it was.
+	var buf bytes.Buffer
+	err := conduitclient.GenerateStructs(&buf, conduitclient.GenerateOptions{
+		Package:      *pkg,
+		Constants:    *consts,
+		QueryHelpers: *helpers,
+		Nullable:     *nullable,
+	}, schemas...)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = buf.WriteTo(os.Stdout)
+		return err
+	}
+	return ioutil.WriteFile(*out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	conduitclient "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+	"github.com/spf13/pflag"
+)
+
+// runHealth implements the "health" subcommand. It fails when a server checked is
+// unreachable or rejects the token, so it can back a readiness probe:
+//
+//	conduit health --timeout 5s
+func runHealth(client *conduitclient.ConduitClient, args []string) error {
+	flags := pflag.NewFlagSet("health", pflag.ContinueOnError)
+	timeout := flags.Duration("timeout", 10*time.Second, "Give up on the check after this long.")
+	servers := flags.StringSlice("server", nil, "More Conduit servers to check alongside CONDUIT_SERVER.")
+	asJson := flags.Bool("json", false, "Print the report as JSON.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	client.Servers = append(client.Servers, *servers...)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	reports := client.HealthCheck(ctx)
+	if *asJson {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	}
+	var failed []string
+	for _, r := range reports {
+		if !*asJson {
+			printHealth(r)
+		}
+		if !r.Healthy() {
+			failed = append(failed, r.Server)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unhealthy: %v", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func printHealth(r conduitclient.HealthReport) {
+	status := "ok"
+	if !r.Healthy() {
+		status = "FAIL"
+	}
+	fmt.Printf("%v\t%v\tlatency %v", r.Server, status, r.Latency.Round(time.Millisecond))
+	if r.Version != "" {
+		fmt.Printf("\tversion %v", r.Version)
+	}
+	if len(r.Capabilities) > 0 {
+		fmt.Printf("\tcapabilities %v", strings.Join(r.Capabilities, ","))
+	}
+	if r.Err != nil {
+		fmt.Printf("\t%v", r.Err)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	conduitclient "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+	"github.com/spf13/pflag"
+)
+
+// runLogin implements the "login" subcommand. It signs in through the server's device
+// login when it has one, or prompts for the token, checks the token works, and stores it
+// for the profile so later commands and NewClient pick it up.
+func runLogin(cfg *conduitclient.Config, args []string) error {
+	flags := pflag.NewFlagSet("login", pflag.ContinueOnError)
+	server := flags.String("server", cfg.Server, "The Conduit server to log in to.")
+	noDevice := flags.Bool("no-device", false, "Prompt for a token rather than logging in through the browser.")
+	timeout := flags.Duration("timeout", 10*time.Minute, "Give up on the login after this long.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	in := bufio.NewReader(os.Stdin)
+	if *server == "" {
+		*server = prompt(in, "Conduit server: ")
+		if *server == "" {
+			return fmt.Errorf("login needs a server")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := &conduitclient.ConduitClient{ConduitServer: *server}
+	var err error
+	if client.HTTPClient, err = cfg.HTTPClient(); err != nil {
+		return err
+	}
+	token := ""
+	if !*noDevice {
+		token, err = client.DeviceLogin(ctx, func(code conduitclient.DeviceCode) {
+			fmt.Printf("Open %v and enter the code %v\n", code.VerificationURI, code.UserCode)
+		})
+		if err != nil && err != conduitclient.ErrDeviceLoginUnsupported {
+			return err
+		}
+	}
+	if token == "" {
+		token = promptSecret(in, "Conduit token: ")
+		if token == "" {
+			return fmt.Errorf("login needs a token")
+		}
+	}
+
+	client.ConduitToken = token
+	if _, err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("could not log in to %v: %v", *server, err)
+	}
+	profile := profileName(cfg)
+	if err := conduitclient.SaveCredentials(profile, conduitclient.Credentials{Server: *server, Token: token}); err != nil {
+		return err
+	}
+	path, _ := conduitclient.CredentialsPath()
+	fmt.Printf("Logged in to %v as profile %v. Credentials saved in %v\n", *server, profile, path)
+	return nil
+}
+
+// runLogout implements the "logout" subcommand, forgetting the profile's stored credentials.
+func runLogout(cfg *conduitclient.Config, args []string) error {
+	profile := profileName(cfg)
+	removed, err := conduitclient.DeleteCredentials(profile)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Printf("No stored credentials for profile %v\n", profile)
+		return nil
+	}
+	fmt.Printf("Logged out of profile %v\n", profile)
+	return nil
+}
+
+func profileName(cfg *conduitclient.Config) string {
+	if cfg.Profile != "" {
+		return cfg.Profile
+	}
+	return conduitclient.DefaultProfile
+}
+
+func prompt(in *bufio.Reader, label string) string {
+	fmt.Print(label)
+	line, _ := in.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// promptSecret reads a line without echoing it, when stdin is a terminal stty can control.
+func promptSecret(in *bufio.Reader, label string) string {
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			stty("echo")
+			fmt.Println()
+		}()
+	}
+	return prompt(in, label)
+}
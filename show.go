@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	conduitclient "github.com/BlueprintConsulting/Conduit-GoSDK/conduit"
+	"github.com/BlueprintConsulting/Conduit-GoSDK/conduit/render"
+	"github.com/spf13/pflag"
+)
+
+// renderFlags adds the output flags shared by the commands that print tables.
+func renderFlags(flags *pflag.FlagSet) func() (render.Options, error) {
+	format := flags.String("format", "unicode", "Output format: ascii, unicode, markdown, html, csv, json or yaml.")
+	maxWidth := flags.Int("max-width", 0, "Widest a table may be (default: the terminal's width).")
+	maxColumnWidth := flags.Int("max-column-width", 40, "Truncate longer values; 0 for no limit.")
+	null := flags.String("null", "", "Shown for missing values (default NULL, or nothing in CSV).")
+	return func() (render.Options, error) {
+		f, err := render.ParseFormat(*format)
+		return render.Options{Format: f, MaxWidth: *maxWidth, MaxColumnWidth: *maxColumnWidth, Null: *null}, err
+	}
+}
+
+// runShow implements the "databases", "tables", "schema" and "query" subcommands:
+//
+//	conduit databases
+//	conduit tables DATABASE
+//	conduit schema DATABASE TABLE
+//	conduit query --format csv "SELECT ..."
+func runShow(client *conduitclient.ConduitClient, command string, args []string) error {
+	flags := pflag.NewFlagSet(command, pflag.ContinueOnError)
+	options := renderFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	opts, err := options()
+	if err != nil {
+		return err
+	}
+	args = flags.Args()
+	want := map[string]int{"databases": 0, "tables": 1, "schema": 2, "query": 1}[command]
+	if len(args) != want {
+		return fmt.Errorf("%v takes %v arguments, got %v", command, want, len(args))
+	}
+
+	var table render.Table
+	switch command {
+	case "databases":
+		table = render.Databases(client.GetDatabases())
+	case "tables":
+		table = render.Tables(client.GetTables(args[0]))
+	case "schema":
+		table = render.Schema(client.GetTableSchema(args[0], args[1]))
+	case "query":
+		if err := client.ExecuteQuery(context.Background(), args[0]); err != nil {
+			return err
+		}
+		table = render.Results(client.Query.QueryResults...)
+	}
+	return render.Render(os.Stdout, table, opts)
+}